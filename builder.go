@@ -0,0 +1,247 @@
+package lnk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Builder creates new lnk files from scratch. Each setter returns the Builder
+// so calls can be chained. Invalid values are recorded and returned by Build.
+//
+//	f, err := lnk.NewBuilder().
+//		Target(`C:\Windows\System32\notepad.exe`).
+//		Arguments("readme.txt").
+//		WorkingDir(`C:\Users\Public`).
+//		Build()
+type Builder struct {
+	target      string
+	arguments   string
+	workingDir  string
+	iconPath    string
+	iconIndex   int32
+	hotKey      string
+	showCommand string
+	description string
+	codePage    int
+	errs        []string
+}
+
+// NewBuilder returns an empty Builder. The target must be set before calling
+// Build.
+func NewBuilder() *Builder {
+	return &Builder{
+		hotKey:      "No Key Assigned",
+		showCommand: "SW_SHOWNORMAL",
+		codePage:    1252,
+	}
+}
+
+// Target sets the absolute path to the link target. It can be a local path
+// (e.g. C:\dir\file.exe) or a UNC path (e.g. \\server\share\dir\file.exe).
+// Forward slashes are converted to backslashes.
+func (b *Builder) Target(path string) *Builder {
+	path = strings.Replace(path, "/", `\`, -1)
+	if !isLocalPath(path) && !isUNCPath(path) {
+		b.errs = append(b.errs, fmt.Sprintf("target must be a local or UNC path - got %q", path))
+	}
+	b.target = path
+	return b
+}
+
+// Arguments sets the command-line arguments of the target.
+func (b *Builder) Arguments(args string) *Builder {
+	b.arguments = args
+	return b
+}
+
+// WorkingDir sets the working directory of the target.
+func (b *Builder) WorkingDir(dir string) *Builder {
+	b.workingDir = dir
+	return b
+}
+
+// Icon sets the icon location and the index of the icon inside it.
+func (b *Builder) Icon(path string, index int32) *Builder {
+	b.iconPath = path
+	b.iconIndex = index
+	return b
+}
+
+// HotKey sets the hotkey in the same format returned by HotKey (e.g.
+// "CTRL+F12" or "ALT+NUM LOCK").
+func (b *Builder) HotKey(hotkey string) *Builder {
+	if hotKeyValue(hotkey) == 0 && hotkey != "No Key Assigned" {
+		b.errs = append(b.errs, fmt.Sprintf("invalid hotkey - got %q", hotkey))
+	}
+	b.hotKey = hotkey
+	return b
+}
+
+// ShowCommand sets the window state of the target. Valid values are
+// SW_SHOWNORMAL, SW_SHOWMAXIMIZED and SW_SHOWMINNOACTIVE.
+func (b *Builder) ShowCommand(cmd string) *Builder {
+	switch cmd {
+	case "SW_SHOWNORMAL", "SW_SHOWMAXIMIZED", "SW_SHOWMINNOACTIVE":
+	default:
+		b.errs = append(b.errs, fmt.Sprintf("invalid show command - got %q", cmd))
+	}
+	b.showCommand = cmd
+	return b
+}
+
+// Description sets the NameString displayed to users.
+func (b *Builder) Description(desc string) *Builder {
+	b.description = desc
+	return b
+}
+
+// CodePage sets the Windows code page of the ANSI paths in LinkInfo, see
+// WithCodePage. Characters that are not in the code page are stored as '?'
+// and the path is also stored in Unicode. The default is 1252.
+func (b *Builder) CodePage(cp int) *Builder {
+	if cp == CodePageAuto || !validCodePage(cp) {
+		b.errs = append(b.errs, fmt.Sprintf("invalid code page - got %d", cp))
+	}
+	b.codePage = cp
+	return b
+}
+
+// Build returns a populated LnkFile. The LinkFlags, LinkInfo and StringData
+// are set based on the values in the Builder. The result can be passed to
+// Write.
+func (b *Builder) Build() (f LnkFile, err error) {
+	if b.target == "" {
+		b.errs = append(b.errs, "target is not set")
+	}
+	if len(b.errs) != 0 {
+		return f, fmt.Errorf("golnk.Builder.Build: %s", strings.Join(b.errs, ", "))
+	}
+
+	flags := FlagMap{
+		"HasLinkInfo": true,
+		"IsUnicode":   true,
+	}
+
+	// StringData.
+	if b.description != "" {
+		flags["HasName"] = true
+		f.StringData.NameString = b.description
+	}
+	if b.workingDir != "" {
+		flags["HasWorkingDir"] = true
+		f.StringData.WorkingDir = b.workingDir
+	}
+	if b.arguments != "" {
+		flags["HasArguments"] = true
+		f.StringData.CommandLineArguments = b.arguments
+	}
+	if b.iconPath != "" {
+		flags["HasIconLocation"] = true
+		f.StringData.IconLocation = b.iconPath
+	}
+
+	f.Header = ShellLinkHeaderSection{
+		Magic:          headerSize,
+		LinkFlags:      flags,
+		FileAttributes: FlagMap{},
+		IconIndex:      b.iconIndex,
+		ShowCommand:    b.showCommand,
+		HotKey:         b.hotKey,
+	}
+
+	if isUNCPath(b.target) {
+		f.LinkInfo = networkLinkInfo(b.target, b.codePage)
+	} else {
+		f.LinkInfo = localLinkInfo(b.target, b.codePage)
+	}
+
+	f.CodePage = b.codePage
+
+	// The empty TerminalBlock ends the file.
+	f.DataBlocks = ExtraDataSection{}
+	return f, nil
+}
+
+// localLinkInfo returns a LinkInfoSection with a VolumeID and LocalBasePath
+// for a local path. If the path is not in the code page, the Unicode fields
+// are added.
+func localLinkInfo(path string, cp int) (li LinkInfoSection) {
+	li.codePage = cp
+	li.LinkInfoHeaderSize = 0x1C
+	li.LinkInfoFlags = 0x01
+	li.LinkInfoFlagsStr = []string{linkInfoFlags[0]}
+	li.VolID = VolID{
+		DriveType:         "DRIVE_FIXED",
+		DriveSerialNumber: "0x00000000",
+	}
+	li.LocalBasePath, li.LocalBasePathRaw = toANSI(path, cp)
+	if li.LocalBasePath != path {
+		li.LinkInfoHeaderSize = 0x24
+		li.LocalBasePathUnicode = path
+	}
+	return li
+}
+
+// networkLinkInfo returns a LinkInfoSection with a CommonNetworkRelativeLink
+// for a UNC path. NetName is \\server\share and the rest of the path goes
+// into CommonPathSuffix.
+func networkLinkInfo(path string, cp int) (li LinkInfoSection) {
+	// Split \\server\share\rest into [server share rest].
+	parts := strings.SplitN(strings.TrimPrefix(path, `\\`), `\`, 3)
+	netName := `\\` + parts[0] + `\` + parts[1]
+	var suffix string
+	if len(parts) == 3 {
+		suffix = parts[2]
+	}
+
+	li.codePage = cp
+	li.LinkInfoHeaderSize = 0x1C
+	li.LinkInfoFlags = 0x02
+	li.LinkInfoFlagsStr = []string{linkInfoFlags[1]}
+	li.NetworkRelativeLink = CommonNetworkRelativeLink{
+		// ValidNetType.
+		CommonNetworkRelativeLinkFlags:    0x02,
+		CommonNetworkRelativeLinkFlagsStr: []string{commonNetworkRelativeLinkFlags[1]},
+		NetNameOffset:                     0x14,
+		NetworkProviderType:               networkProviderType(wnncNetLanman),
+	}
+	li.NetworkRelativeLink.codePage = cp
+	li.NetworkRelativeLink.NetName, li.NetworkRelativeLink.NetNameRaw = toANSI(netName, cp)
+	li.CommonPathSuffix, li.CommonPathSuffixRaw = toANSI(suffix, cp)
+	if li.NetworkRelativeLink.NetName != netName || li.CommonPathSuffix != suffix {
+		li.LinkInfoHeaderSize = 0x24
+		li.NetworkRelativeLink.NetNameOffset = 0x1C
+		li.NetworkRelativeLink.NetNameUnicode = netName
+		li.CommonPathSuffixUnicode = suffix
+	}
+	return li
+}
+
+// wnncNetLanman is the NetworkProviderType of Windows file shares.
+const wnncNetLanman = 0x00020000
+
+// isLocalPath returns true for paths that start with a drive letter such as
+// C:\.
+func isLocalPath(path string) bool {
+	if len(path) < 3 || path[1] != ':' || path[2] != '\\' {
+		return false
+	}
+	c := path[0] | 0x20 // Lowercase.
+	return 'a' <= c && c <= 'z'
+}
+
+// isUNCPath returns true for paths like \\server\share.
+func isUNCPath(path string) bool {
+	if !strings.HasPrefix(path, `\\`) {
+		return false
+	}
+	parts := strings.SplitN(strings.TrimPrefix(path, `\\`), `\`, 3)
+	return len(parts) >= 2 && parts[0] != "" && parts[1] != ""
+}
+
+// toANSI encodes s in the code page and returns the bytes and the string they
+// decode to. Characters that are not in the code page become '?'.
+func toANSI(s string, cp int) (string, []byte) {
+	raw := encodeANSI(s, cp)
+	return decodeANSI(raw, cp), raw
+}
@@ -0,0 +1,87 @@
+package lnk
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		wantErr bool
+	}{
+		{"local", NewBuilder().Target(`C:\Windows\System32\notepad.exe`), false},
+		{"local-unicode", NewBuilder().Target(`C:\Users\Дмитрий\文档\😀.txt`).WorkingDir(`C:\Users\Дмитрий`), false},
+		{"local-code-page", NewBuilder().Target(`C:\Users\Дмитрий\app.exe`).CodePage(1251), false},
+		{"local-forward-slash", NewBuilder().Target("C:/Program Files/app.exe"), false},
+		{"unc", NewBuilder().Target(`\\server\share\dir\file.exe`), false},
+		{"unc-share-only", NewBuilder().Target(`\\server\share`), false},
+		{"all-fields", NewBuilder().
+			Target(`C:\Windows\System32\cmd.exe`).
+			Arguments("/c dir").
+			WorkingDir(`C:\Users\Public`).
+			Icon(`C:\Windows\System32\shell32.dll`, 3).
+			HotKey("CTRL+F12").
+			ShowCommand("SW_SHOWMINNOACTIVE").
+			Description("Deployment shortcut"), false},
+		{"no-target", NewBuilder(), true},
+		{"relative-target", NewBuilder().Target(`dir\file.exe`), true},
+		{"invalid-hotkey", NewBuilder().Target(`C:\a.exe`).HotKey("CTRL+TAB"), true},
+		{"invalid-show-command", NewBuilder().Target(`C:\a.exe`).ShowCommand("SW_HIDE"), true},
+		{"invalid-code-page", NewBuilder().Target(`C:\a.exe`).CodePage(1), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := tt.builder.Build()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Build() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			// Write the file and parse it again.
+			var buf bytes.Buffer
+			if err := Write(&buf, want); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := Read(&buf, uint64(buf.Len()), WithCodePage(want.CodePage))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+
+			if got.Header.HotKey != want.Header.HotKey {
+				t.Errorf("HotKey = %v, want %v", got.Header.HotKey, want.Header.HotKey)
+			}
+			if got.Header.ShowCommand != want.Header.ShowCommand {
+				t.Errorf("ShowCommand = %v, want %v", got.Header.ShowCommand, want.Header.ShowCommand)
+			}
+			if got.Header.IconIndex != want.Header.IconIndex {
+				t.Errorf("IconIndex = %v, want %v", got.Header.IconIndex, want.Header.IconIndex)
+			}
+			if !reflect.DeepEqual(stringFields(got.StringData), stringFields(want.StringData)) {
+				t.Errorf("StringData = %+v, want %+v", got.StringData, want.StringData)
+			}
+			if got.LinkInfo.LocalBasePath != want.LinkInfo.LocalBasePath ||
+				got.LinkInfo.LocalBasePathUnicode != want.LinkInfo.LocalBasePathUnicode ||
+				got.LinkInfo.CommonPathSuffix != want.LinkInfo.CommonPathSuffix ||
+				got.LinkInfo.NetworkRelativeLink.NetName != want.LinkInfo.NetworkRelativeLink.NetName {
+				t.Errorf("LinkInfo = %+v, want %+v", got.LinkInfo, want.LinkInfo)
+			}
+		})
+	}
+}
+
+// stringFields returns s without the raw strings, Span and code page, which
+// depend on how the section was created.
+func stringFields(s StringDataSection) StringDataSection {
+	return StringDataSection{
+		NameString:           s.NameString,
+		RelativePath:         s.RelativePath,
+		WorkingDir:           s.WorkingDir,
+		CommandLineArguments: s.CommandLineArguments,
+		IconLocation:         s.IconLocation,
+	}
+}
@@ -5,24 +5,25 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"unicode/utf16"
 	"unicode/utf8"
 )
 
-// byteMaskuint16 returns one of the two bytes from a uint16.
+// byteMaskuint16 returns one of the two bytes from a uint16. Returns 0 if n is
+// not 0 or 1.
 func byteMaskuint16(b uint16, n int) uint16 {
-	// Maybe we should not panic, hmm.
-	if n < 0 || n > 2 {
-		panic(fmt.Sprintf("invalid byte mask, got %d", n))
+	if n < 0 || n > 1 {
+		return 0
 	}
 	mask := uint16(0x000000FF) << uint16(n*8)
 	return (b & mask) >> uint16(n*8)
 }
 
 // bitMaskuint32 returns one of the 32-bits from a uint32.
-// Returns true for 1 and false for 0.
+// Returns true for 1 and false for 0 or if n is not a valid bit number.
 func bitMaskuint32(b uint32, n int) bool {
 	if n < 0 || n > 31 {
-		panic(fmt.Sprintf("invalid bit number, got %d", n))
+		return false
 	}
 	return ((b >> uint(n)) & 1) == 1
 }
@@ -52,7 +53,7 @@ func ReadBytes(b []byte, offset, num int) (out []byte, n int) {
 	The size bytes are added to the start of the []byte to keep the section
 	[]byte intact for later offset use.
 */
-func readSection(r io.Reader, sSize int, maxSize uint64) (data []byte, nr io.Reader, size int, err error) {
+func readSection(r io.Reader, sSize int, maxSize uint64, b *budget) (data []byte, nr io.Reader, size int, err error) {
 	// We are not going to lose data by copying a smaller var into a larger one.
 	var sectionSize uint64
 	switch sSize {
@@ -61,7 +62,7 @@ func readSection(r io.Reader, sSize int, maxSize uint64) (data []byte, nr io.Rea
 		var size16 uint16
 		err = binary.Read(r, binary.LittleEndian, &size16)
 		if err != nil {
-			return data, nr, size, fmt.Errorf("golnk.readSection: read size %d bytes - %s", sSize, err.Error())
+			return data, nr, size, fmt.Errorf("golnk.readSection: read size %d bytes - %w", sSize, err)
 		}
 		sectionSize = uint64(size16)
 		// Add bytes to the start of data []byte.
@@ -71,7 +72,7 @@ func readSection(r io.Reader, sSize int, maxSize uint64) (data []byte, nr io.Rea
 		var size32 uint32
 		err = binary.Read(r, binary.LittleEndian, &size32)
 		if err != nil {
-			return data, nr, size, fmt.Errorf("golnk.readSection: read size %d bytes - %s", sSize, err.Error())
+			return data, nr, size, fmt.Errorf("golnk.readSection: read size %d bytes - %w", sSize, err)
 		}
 		sectionSize = uint64(size32)
 		// Add bytes to the start of data []byte.
@@ -80,7 +81,7 @@ func readSection(r io.Reader, sSize int, maxSize uint64) (data []byte, nr io.Rea
 		// Read uint64 or sectionSize.
 		err = binary.Read(r, binary.LittleEndian, &sectionSize)
 		if err != nil {
-			return data, nr, size, fmt.Errorf("golnk.readSection: read size %d bytes - %s", sSize, err.Error())
+			return data, nr, size, fmt.Errorf("golnk.readSection: read size %d bytes - %w", sSize, err)
 		}
 		// Add bytes to the start of data []byte.
 		data = uint64Byte(sectionSize)
@@ -88,16 +89,24 @@ func readSection(r io.Reader, sSize int, maxSize uint64) (data []byte, nr io.Rea
 		return data, nr, size, fmt.Errorf("golnk.readSection: invalid sSize - got %v", sSize)
 	}
 
+	// The size includes the size field.
+	if sectionSize < uint64(sSize) {
+		return data, nr, size, fmt.Errorf("golnk.readSection: %w - got %d, want at least %d", ErrBadSize, sectionSize, sSize)
+	}
+
 	// Create a []byte of sectionSize-4 and read that many bytes from io.Reader.
 	computedSize := sectionSize - uint64(sSize)
 	if computedSize > maxSize {
-		return data, nr, size, fmt.Errorf("golnk.readSection: invalid computed size got %d; expected a size < %d", computedSize, maxSize)
+		return data, nr, size, fmt.Errorf("golnk.readSection: %w - got %d, want < %d", ErrSizeExceeded, computedSize, maxSize)
+	}
+	if err = b.alloc(computedSize); err != nil {
+		return data, nr, size, fmt.Errorf("golnk.readSection: %w", err)
 	}
 
 	tempData := make([]byte, computedSize)
 	err = binary.Read(r, binary.LittleEndian, &tempData)
 	if err != nil {
-		return data, nr, size, fmt.Errorf("golnk.readSection: read section %d bytes - %s", sectionSize-uint64(sSize), err.Error())
+		return data, nr, size, fmt.Errorf("golnk.readSection: read section %d bytes - %w", sectionSize-uint64(sSize), err)
 	}
 
 	// If this is successful, append it to data []byte.
@@ -109,6 +118,15 @@ func readSection(r io.Reader, sSize int, maxSize uint64) (data []byte, nr io.Rea
 	return data, nr, int(sectionSize), nil
 }
 
+// sectionTail returns the data of a section from offset. An offset after the
+// end of the section returns ErrBadOffset.
+func sectionTail(data []byte, offset uint32) ([]byte, error) {
+	if uint64(offset) > uint64(len(data)) {
+		return nil, fmt.Errorf("%w - offset %d larger than size %d", ErrBadOffset, offset, len(data))
+	}
+	return data[offset:], nil
+}
+
 // readString returns a string of all bytes from the []byte until the first 0x00.
 func readString(data []byte) string {
 	// Find the index of first 0x00.
@@ -121,81 +139,156 @@ func readString(data []byte) string {
 }
 
 // readUnicodeString returns a string of all bytes from the []byte until the
-// first 0x0000.
+// first 0x0000. Invalid UTF-16 is replaced with U+FFFD, use decodeUTF16 to
+// detect it.
 func readUnicodeString(data []byte) string {
+	str, _ := decodeUTF16(data)
+	return str
+}
 
-	// Read two bytes at a time and convert to rune, stop if both are 0x0000 or
-	// we have reached the end of the input.
-	var runes []rune
-	for bitIndex := 0; bitIndex < len(data)/2; bitIndex++ {
-		if data[bitIndex*2] == 0x00 && data[(bitIndex*2)+1] == 0x00 {
-			return string(runes)
+// decodeUTF16 decodes UTF-16LE bytes until the first 0x0000 or the end of the
+// []byte. Unpaired surrogates and a trailing odd byte are replaced with U+FFFD
+// and returned as an error wrapping ErrBadString along with the rest of the
+// string.
+func decodeUTF16(data []byte) (string, error) {
+	// Read two bytes at a time, stop if both are 0x0000.
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		u := uint16Little(data[i:])
+		if u == 0 {
+			return utf16String(units)
 		}
-		r, _ := utf8.DecodeRune(data[bitIndex*2:])
-		runes = append(runes, r)
+		units = append(units, u)
 	}
-	return string(runes)
+	str, err := utf16String(units)
+	if len(data)%2 != 0 {
+		str += string(utf8.RuneError)
+		if err == nil {
+			err = fmt.Errorf("%w - odd number of bytes - got %d", ErrBadString, len(data))
+		}
+	}
+	return str, err
 }
 
-// readStringData reads a uint16 as size and then reads that many bytes
-// (*2 for unicode) into a string. The string is not null-terminated.
-// TODO: Write tests.
-func readStringData(r io.Reader, isUnicode bool) (str string, err error) {
-	// Recover in case we attempt to read more bytes than there is in the reader.
-	defer func() {
-		if r := recover(); r != nil {
-			// If panic occurs, return this error message
-			err = fmt.Errorf("golnk.readStringData: not enough bytes in reader")
+// utf16String converts UTF-16 code units to a string. Unpaired surrogates are
+// replaced with U+FFFD and the first one is returned as an error wrapping
+// ErrBadString.
+func utf16String(units []uint16) (string, error) {
+	var err error
+	runes := make([]rune, 0, len(units))
+	for i := 0; i < len(units); i++ {
+		u := rune(units[i])
+		switch {
+		case !utf16.IsSurrogate(u):
+			runes = append(runes, u)
+		case i+1 < len(units) && utf16.DecodeRune(u, rune(units[i+1])) != utf8.RuneError:
+			runes = append(runes, utf16.DecodeRune(u, rune(units[i+1])))
+			i++
+		default:
+			runes = append(runes, utf8.RuneError)
+			if err == nil {
+				err = fmt.Errorf("%w - unpaired surrogate 0x%04X at character %d", ErrBadString, u, i)
+			}
 		}
-	}()
+	}
+	return string(runes), err
+}
 
+// readStringData reads a uint16 as size and then reads that many bytes
+// (*2 for unicode) into a string. The string is not null-terminated. The
+// length is checked against the limits in b before the bytes are allocated.
+// Invalid UTF-16 is decoded like in decodeUTF16, the string is returned with
+// an error wrapping ErrBadString.
+func readStringData(r io.Reader, isUnicode bool, b *budget) (str string, err error) {
 	var size uint16
 	err = binary.Read(r, binary.LittleEndian, &size)
 	if err != nil {
-		return str, fmt.Errorf("golnk.readStringData: read size - %s", err.Error())
+		return str, fmt.Errorf("golnk.readStringData: read size - %w", err)
+	}
+	if err = b.str(int(size)); err != nil {
+		return str, fmt.Errorf("golnk.readStringData: %w", err)
 	}
+	// size*2 does not fit in a uint16 for long Unicode strings.
+	n := int(size)
 	if isUnicode {
-		size = size * 2
+		n *= 2
+	}
+	if err = b.alloc(uint64(n)); err != nil {
+		return str, fmt.Errorf("golnk.readStringData: %w", err)
 	}
-	b := make([]byte, size)
-	err = binary.Read(r, binary.LittleEndian, &b)
+	data := make([]byte, n)
+	err = binary.Read(r, binary.LittleEndian, &data)
 	if err != nil {
-		return str, fmt.Errorf("golnk.readStringData: read bytes - %s", err.Error())
+		return str, fmt.Errorf("golnk.readStringData: read bytes - %w", err)
 	}
-	// If unicode, read every 2 byte and get a rune.
+	// If unicode, read every 2 byte as a UTF-16 code unit.
 	if isUnicode {
-		var runes []rune
-		for bitIndex := 0; bitIndex < int(size)/2; bitIndex++ {
-			r, _ := utf8.DecodeRune(b[bitIndex*2:])
-			runes = append(runes, r)
+		units := make([]uint16, n/2)
+		for bitIndex := range units {
+			units[bitIndex] = uint16Little(data[bitIndex*2:])
 		}
-		return string(runes), nil
+		return utf16String(units)
 	}
-	return string(b), nil
+	return string(data), nil
+}
+
+// unicodeBytes converts a string to UTF-16LE bytes. It does not add a
+// null-terminator.
+func unicodeBytes(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	b := make([]byte, len(units)*2)
+	for i, u := range units {
+		binary.LittleEndian.PutUint16(b[i*2:], u)
+	}
+	return b
+}
+
+// nullString returns the bytes of a null-terminated string, Unicode
+// strings get a two byte terminator.
+func nullString(s string, isUnicode bool) []byte {
+	if isUnicode {
+		return append(unicodeBytes(s), 0x00, 0x00)
+	}
+	return append([]byte(s), 0x00)
+}
+
+// stringDataBytes is the reverse of readStringData. It returns the uint16
+// character count followed by the string bytes (UTF-16LE for unicode).
+func stringDataBytes(str string, isUnicode bool) ([]byte, error) {
+	b := []byte(str)
+	count := len(b)
+	if isUnicode {
+		b = unicodeBytes(str)
+		count = len(b) / 2
+	}
+	if count > 0xFFFF {
+		return nil, fmt.Errorf("golnk.stringDataBytes: string too long - got %d characters", count)
+	}
+	return append(uint16Byte(uint16(count)), b...), nil
 }
 
 // uint16Little reads a uint16 from []byte and returns the result in Little-Endian.
+// Missing bytes are read as zero.
 func uint16Little(b []byte) uint16 {
-	if len(b) < 2 {
-		panic(fmt.Sprintf("input smaller than two bytes - got %d", len(b)))
-	}
-	return binary.LittleEndian.Uint16(b)
+	var buf [2]byte
+	copy(buf[:], b)
+	return binary.LittleEndian.Uint16(buf[:])
 }
 
 // uint32Little reads a uint32 from []byte and returns the result in Little-Endian.
+// Missing bytes are read as zero.
 func uint32Little(b []byte) uint32 {
-	if len(b) < 4 {
-		panic(fmt.Sprintf("input smaller than four bytes - got %d", len(b)))
-	}
-	return binary.LittleEndian.Uint32(b)
+	var buf [4]byte
+	copy(buf[:], b)
+	return binary.LittleEndian.Uint32(buf[:])
 }
 
 // uint64Little reads a uint64 from []byte and returns the result in Little-Endian.
+// Missing bytes are read as zero.
 func uint64Little(b []byte) uint64 {
-	if len(b) < 8 {
-		panic(fmt.Sprintf("input smaller than eight bytes - got %d", len(b)))
-	}
-	return binary.LittleEndian.Uint64(b)
+	var buf [8]byte
+	copy(buf[:], b)
+	return binary.LittleEndian.Uint64(buf[:])
 }
 
 // uint16Str converts a uint16 to string using fmt.Sprint.
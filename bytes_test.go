@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bytes"
 	"testing"
 )
 
@@ -28,6 +29,7 @@ func Test_uint16Little(t *testing.T) {
 		{"b3", args{b3}, 0xEEFF},
 		{"b4", args{b4}, 0xDDCC},
 		{"b5", args{b5}, 0xAABB},
+		{"short", args{[]byte{0x01}}, 0x0001},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -53,6 +55,7 @@ func Test_uint32Little(t *testing.T) {
 		{"b3", args{b3}, 0x0302EEFF},
 		{"b4", args{b4}, 0x0302DDCC},
 		{"b5", args{b5}, 0x0302AABB},
+		{"short", args{[]byte{0x01, 0x02}}, 0x00000201},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -78,6 +81,7 @@ func Test_uint64Little(t *testing.T) {
 		{"b3", args{b3}, 0x070605040302EEFF},
 		{"b4", args{b4}, 0x070605040302DDCC},
 		{"b5", args{b5}, 0x070605040302AABB},
+		{"short", args{nil}, 0},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -106,6 +110,7 @@ func Test_byteMaskuint16(t *testing.T) {
 		{"byte-1", args{b: 0xBBAA, n: 1}, 0x00BB},
 		{"invalid", args{b: 0x0201, n: 0}, 0x0001},
 		{"byte-1", args{b: 0x0201, n: 1}, 0x0002},
+		{"out-of-range", args{b: 0x0201, n: 2}, 0x0000},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -192,6 +197,10 @@ func Test_readUnicodeString(t *testing.T) {
 		{"normal-0123-2", args{[]byte{0x30, 0x00, 0x31, 0x00, 0x32, 0x00, 0x33, 0x00, 0x00, 0x00, 0x34, 0x00, 0x35, 0x00, 0x36, 0x00}}, "0123"},
 		{"no-0x00", args{[]byte{0x30, 0x00, 0x31, 0x00, 0x32, 0x00, 0x33, 0x00, 0x34, 0x00, 0x35, 0x00, 0x36, 0x00}}, "0123456"},
 		{"start-0x00", args{[]byte{0x00, 0x00, 0x30, 0x31, 0x32, 0x33, 0x34, 0x35, 0x36}}, ""},
+		{"cyrillic", args{[]byte{0x14, 0x04, 0x3C, 0x04, 0x38, 0x04, 0x00, 0x00}}, "Дми"},
+		{"cjk", args{[]byte{0x87, 0x65, 0x63, 0x68, 0x00, 0x00}}, "文档"},
+		{"surrogate-pair", args{[]byte{0x3D, 0xD8, 0x00, 0xDE, 0x00, 0x00}}, "😀"},
+		{"unpaired-surrogate", args{[]byte{0x3D, 0xD8, 0x41, 0x00, 0x00, 0x00}}, "\uFFFDA"},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -201,3 +210,54 @@ func Test_readUnicodeString(t *testing.T) {
 		})
 	}
 }
+
+func Test_decodeUTF16(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr bool
+	}{
+		{"valid", []byte{0x3D, 0xD8, 0x00, 0xDE, 0x41, 0x00}, "😀A", false},
+		{"high-surrogate-at-end", []byte{0x41, 0x00, 0x3D, 0xD8}, "A\uFFFD", true},
+		{"low-surrogate-first", []byte{0x00, 0xDE, 0x41, 0x00, 0x00, 0x00}, "\uFFFDA", true},
+		{"odd-length", []byte{0x41, 0x00, 0x42}, "A\uFFFD", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeUTF16(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("decodeUTF16() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("decodeUTF16() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readStringData(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      []byte
+		isUnicode bool
+		want      string
+		wantErr   bool
+	}{
+		{"ansi", []byte{0x03, 0x00, 'a', 'b', 'c'}, false, "abc", false},
+		{"unicode", []byte{0x03, 0x00, 0x14, 0x04, 0x3D, 0xD8, 0x00, 0xDE}, true, "Д😀", false},
+		{"unicode-invalid", []byte{0x02, 0x00, 0x00, 0xDE, 0x41, 0x00}, true, "\uFFFDA", true},
+		{"short", []byte{0x05, 0x00, 'a'}, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readStringData(bytes.NewReader(tt.data), tt.isUnicode, newBudget(DefaultLimits))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readStringData() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readStringData() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
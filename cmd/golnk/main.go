@@ -0,0 +1,339 @@
+// Command golnk parses Windows shell link (lnk) files.
+//
+// Usage:
+//
+//	golnk parse [file ...]
+//	golnk dump [-section name] [file ...]
+//	golnk hexdump [-color] [file ...]
+//	golnk json [file ...]
+//	golnk validate [file ...]
+//	golnk extract-overlay [-o file] [file ...]
+//
+// All commands accept -codepage to set the code page of ANSI strings and
+// -lenient to parse damaged files. validate always parses in lenient mode and
+// reports the parser warnings as findings. Files are read from stdin if no
+// file is passed or the file is "-".
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	lnk "github.com/parsiya/golnk"
+)
+
+// command is a golnk subcommand.
+type command struct {
+	// usage is printed in the help.
+	usage string
+	// run processes one parsed file. data is the contents of the file.
+	run func(name string, data []byte, f lnk.LnkFile, w io.Writer) error
+	// flags returns the flag set of the subcommand, can be nil.
+	flags func(fs *flag.FlagSet)
+	// check returns an error if the flags cannot be used with the files, can
+	// be nil.
+	check func(files []string) error
+	// lenient parses the files in lenient mode without the -lenient flag.
+	lenient bool
+}
+
+// Flag values.
+var (
+	section  string
+	codePage int
+	lenient  bool
+	color    bool
+	output   string
+)
+
+var commands = map[string]command{
+	"parse": {
+		usage: "print all sections in tables",
+		run:   parse,
+	},
+	"dump": {
+		usage: "hex dump sections",
+		run:   dump,
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&section, "section", "all",
+				"section to dump: header, idlist, linkinfo, stringdata, extradata or all")
+		},
+	},
+	"hexdump": {
+		usage: "hex dump the file with the structure and field of each byte",
+		run:   hexdump,
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&color, "color", false, "colour each section")
+		},
+	},
+	"json": {
+		usage: "print the parsed file in JSON",
+		run:   toJSON,
+	},
+	"validate": {
+		usage:   "report deviations from the specification, fails on errors",
+		run:     validate,
+		lenient: true,
+	},
+	"extract-overlay": {
+		usage: "write the data after the ExtraData section to a file",
+		run:   extractOverlay,
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&output, "o", "",
+				"output file, \"-\" for stdout, only with one file. Defaults to <file>.overlay or stdout for stdin")
+		},
+		check: func(files []string) error {
+			// Each file would overwrite the overlay of the previous one.
+			if output != "" && output != "-" && len(files) > 1 {
+				return fmt.Errorf("-o needs one file, got %d", len(files))
+			}
+			return nil
+		},
+	},
+}
+
+// commandOrder is the order of commands in the help.
+var commandOrder = []string{"parse", "dump", "hexdump", "json", "validate", "extract-overlay"}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command in args and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "golnk: unknown command %q\n", args[0])
+		usage(stderr)
+		return 2
+	}
+
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.IntVar(&codePage, "codepage", lnk.CodePageAuto,
+		"Windows code page of ANSI strings, e.g. 1252, 1251 or 932. 0 detects it")
+	fs.BoolVar(&lenient, "lenient", false, "parse damaged files and print warnings instead of failing")
+	if cmd.flags != nil {
+		cmd.flags(fs)
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+	lenient = lenient || cmd.lenient
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	if cmd.check != nil {
+		if err := cmd.check(files); err != nil {
+			fmt.Fprintf(stderr, "golnk: %s\n", err.Error())
+			return 2
+		}
+	}
+
+	code := 0
+	for _, name := range files {
+		data, f, err := readFile(name, stdin)
+		if err == nil {
+			err = cmd.run(name, data, f, stdout)
+		}
+		if err != nil {
+			fmt.Fprintf(stderr, "golnk: %s: %s\n", name, err.Error())
+			code = 1
+		}
+	}
+	return code
+}
+
+// usage prints the commands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: golnk <command> [flags] [file ...]")
+	fmt.Fprintln(w, "Files are read from stdin if no file is passed or the file is \"-\".")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, name := range commandOrder {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nRun golnk <command> -h to see the flags of a command.")
+}
+
+// readFile reads and parses the file or stdin if name is "-".
+func readFile(name string, stdin io.Reader) ([]byte, lnk.LnkFile, error) {
+	// The file is in memory, reading the overlay does not consume anything.
+	opts := []lnk.Option{lnk.WithCodePage(codePage), lnk.WithOverlay()}
+	if lenient {
+		opts = append(opts, lnk.Lenient())
+	}
+	r := stdin
+	if name != "-" {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, lnk.LnkFile{}, fmt.Errorf("read file - %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	// Read one byte more than the limit to detect larger files.
+	max := lnk.DefaultLimits.MaxAlloc
+	data, err := ioutil.ReadAll(io.LimitReader(r, max+1))
+	if err != nil {
+		return nil, lnk.LnkFile{}, fmt.Errorf("read file - %w", err)
+	}
+	if int64(len(data)) > max {
+		return nil, lnk.LnkFile{}, fmt.Errorf("read file - larger than %d bytes - %w", max, lnk.ErrLimitExceeded)
+	}
+	f, err := lnk.Read(bytes.NewReader(data), uint64(len(data)), opts...)
+	return data, f, err
+}
+
+// parse prints the section Stringers.
+func parse(name string, data []byte, f lnk.LnkFile, w io.Writer) error {
+	fmt.Fprintf(w, "File: %s\n", name)
+	fmt.Fprintln(w, f.Header)
+	if f.Header.LinkFlags["HasLinkTargetIDList"] {
+		fmt.Fprintln(w, f.IDList)
+	}
+	if f.Header.LinkFlags["HasLinkInfo"] {
+		fmt.Fprintln(w, f.LinkInfo)
+	}
+	fmt.Fprintln(w, f.StringData)
+	fmt.Fprintln(w, f.DataBlocks)
+
+	t := f.Target()
+	fmt.Fprintf(w, "Target: %s (%s)\n", t.Path, t.Source)
+	fmt.Fprintf(w, "Code page: %d\n", f.CodePage)
+	if f.Overlay.Size > 0 {
+		fmt.Fprintf(w, "Overlay: %s\n", f.Overlay)
+	}
+	for _, warn := range f.Warnings {
+		fmt.Fprintf(w, "Warning: %s\n", warn)
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// dump prints the hex dump of the sections.
+func dump(name string, data []byte, f lnk.LnkFile, w io.Writer) error {
+	switch section {
+	case "all", "header", "idlist", "linkinfo", "stringdata", "extradata":
+	default:
+		return fmt.Errorf("invalid section %q", section)
+	}
+	all := section == "all"
+	fmt.Fprintf(w, "File: %s\n", name)
+
+	if all || section == "header" {
+		fmt.Fprintf(w, "Header\n%s\n", f.Header.Dump())
+	}
+	if (all || section == "idlist") && f.Header.LinkFlags["HasLinkTargetIDList"] {
+		data, err := f.IDList.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "LinkTargetIDList\n%s\n", hex.Dump(data))
+	}
+	if (all || section == "linkinfo") && f.Header.LinkFlags["HasLinkInfo"] {
+		fmt.Fprintf(w, "LinkInfo\n%s\n", f.LinkInfo.Dump())
+	}
+	if all || section == "stringdata" {
+		data, err := f.StringData.Marshal(f.Header.LinkFlags)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "StringData\n%s\n", hex.Dump(data))
+	}
+	if all || section == "extradata" {
+		for _, b := range f.DataBlocks.Blocks {
+			fmt.Fprintf(w, "%s\n%s\n", b.Type, b.Dump())
+		}
+	}
+	return nil
+}
+
+// hexdump prints the annotated hex dump of the file.
+func hexdump(name string, data []byte, f lnk.LnkFile, w io.Writer) error {
+	dump, err := f.AnnotatedDump(data, lnk.DumpOptions{Color: color})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "File: %s\n", name)
+	fmt.Fprintln(w, dump)
+	return nil
+}
+
+// jsonFile is printed by the json command.
+type jsonFile struct {
+	Filename string
+	Target   lnk.TargetInfo
+	Lnk      lnk.LnkFile
+}
+
+// toJSON prints the file in JSON.
+func toJSON(name string, data []byte, f lnk.LnkFile, w io.Writer) error {
+	out, err := json.MarshalIndent(jsonFile{Filename: name, Target: f.Target(), Lnk: f}, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+// validate prints the findings of Validate. Returns an error if there is a
+// finding with error severity.
+func validate(name string, data []byte, f lnk.LnkFile, w io.Writer) error {
+	fmt.Fprintf(w, "File: %s\n", name)
+	findings := f.Validate()
+	if len(findings) == 0 {
+		fmt.Fprintln(w, "No findings")
+	}
+	errs := 0
+	for _, fi := range findings {
+		fmt.Fprintln(w, fi)
+		if fi.Severity == lnk.SeverityError {
+			errs++
+		}
+	}
+	fmt.Fprintln(w)
+	if errs > 0 {
+		return fmt.Errorf("%d findings with error severity", errs)
+	}
+	return nil
+}
+
+// extractOverlay writes the overlay to the -o file, <name>.overlay or w if
+// the file is stdin. Returns an error if there is no overlay.
+func extractOverlay(name string, data []byte, f lnk.LnkFile, w io.Writer) error {
+	o := f.Overlay
+	if o.Size == 0 {
+		return fmt.Errorf("no overlay")
+	}
+	// The overlay is at the end of the file.
+	payload := data[o.Offset:]
+
+	out := output
+	if out == "" {
+		out = name + ".overlay"
+		if name == "-" {
+			out = "-"
+		}
+	}
+	if out == "-" {
+		_, err := w.Write(payload)
+		return err
+	}
+	if err := ioutil.WriteFile(out, payload, 0644); err != nil {
+		return fmt.Errorf("write overlay - %s", err.Error())
+	}
+	fmt.Fprintf(w, "%s: wrote %s to %s\n", name, o, out)
+	return nil
+}
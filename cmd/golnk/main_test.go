@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	lnk "github.com/parsiya/golnk"
+)
+
+func TestRun(t *testing.T) {
+	sample := "../../test/nem.test"
+	data, err := ioutil.ReadFile(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withOverlay := append(data[:len(data):len(data)], "PK\x03\x04payload"...)
+
+	// Point VolumeIDOffset outside the LinkInfo.
+	f, err := lnk.Read(bytes.NewReader(data), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	damaged := append([]byte(nil), data...)
+	for _, fi := range f.Offsets() {
+		if fi.Name == "LinkInfo.VolumeIDOffset" {
+			damaged[fi.Span.Offset] = 0xFF
+		}
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    []byte
+		wantCode int
+		want     string // Substring of stdout.
+	}{
+		{"parse", []string{"parse", sample}, nil, 0, "HasherResults.xlsx (LinkInfo.LocalBasePath)"},
+		{"parse-stdin", []string{"parse"}, data, 0, "File: -"},
+		{"dump-linkinfo", []string{"dump", "-section", "linkinfo", sample}, nil, 0, "LinkInfo\n00000000  66 00 00 00"},
+		{"hexdump", []string{"hexdump", sample}, nil, 0, "ShellLinkHeader.LinkFlags"},
+		{"dump-invalid", []string{"dump", "-section", "foo", sample}, nil, 1, ""},
+		{"multiple", []string{"parse", sample, "missing.lnk", sample}, nil, 1, "File: " + sample},
+		{"validate", []string{"validate", sample}, nil, 0, "No findings"},
+		{"validate-damaged", []string{"validate"}, damaged, 1, "LNK001 error LinkInfo: VolumeIDOffset"},
+		{"validate-warning", []string{"validate", "../../test/test.lnk.bak"}, nil, 0, "LNK505 warning"},
+		{"parse-overlay", []string{"parse"}, withOverlay, 0, "Overlay: 11 bytes at offset 0x2E1 - ZIP"},
+		{"extract-overlay", []string{"extract-overlay"}, withOverlay, 0, "PK\x03\x04payload"},
+		{"extract-multiple", []string{"extract-overlay", "-o", "out.overlay", sample, sample}, nil, 2, ""},
+		{"extract-no-overlay", []string{"extract-overlay", "-o", "-", sample}, nil, 1, ""},
+		{"too-large", []string{"parse"}, make([]byte, lnk.DefaultLimits.MaxAlloc+1), 1, ""},
+		{"unknown", []string{"foo"}, nil, 2, ""},
+		{"none", nil, nil, 2, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, bytes.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run() = %d, want %d - stderr: %s", code, tt.wantCode, stderr.String())
+			}
+			if !strings.Contains(stdout.String(), tt.want) {
+				t.Errorf("stdout does not contain %q:\n%s", tt.want, stdout.String())
+			}
+		})
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"json", "../../test/nem.test"}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("run() = %d - stderr: %s", code, stderr.String())
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON - %v", err)
+	}
+	for _, key := range []string{"Filename", "Target", "Lnk"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("JSON has no %s", key)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package lnk
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+// ANSI strings (LocalBasePath, CommonPathSuffix, NetName, DeviceName, the
+// ANSI VolumeLabel, StringData without IsUnicode and some shell item names)
+// are stored in the default code page of the system that created the file.
+// The file does not say which one it is.
+
+// Code pages. Any Windows code page number in codePages can be used.
+const (
+	// CodePageAuto detects the code page, see WithCodePage.
+	CodePageAuto = 0
+	// CodePageUTF8 is used by some tools that write UTF-8 in ANSI fields.
+	CodePageUTF8 = 65001
+)
+
+// codePages maps the supported Windows code pages to their encodings.
+var codePages = map[int]encoding.Encoding{
+	437:  charmap.CodePage437,
+	850:  charmap.CodePage850,
+	866:  charmap.CodePage866,
+	874:  charmap.Windows874,
+	932:  japanese.ShiftJIS,
+	936:  simplifiedchinese.GBK,
+	949:  korean.EUCKR,
+	950:  traditionalchinese.Big5,
+	1250: charmap.Windows1250,
+	1251: charmap.Windows1251,
+	1252: charmap.Windows1252,
+	1253: charmap.Windows1253,
+	1254: charmap.Windows1254,
+	1255: charmap.Windows1255,
+	1256: charmap.Windows1256,
+	1257: charmap.Windows1257,
+	1258: charmap.Windows1258,
+}
+
+// detectOrder is the order code pages are tried when matching ANSI strings
+// with their Unicode versions.
+var detectOrder = []int{
+	1252, 1251, 1250, 932, 936, 949, 950,
+	1253, 1254, 1255, 1256, 1257, 1258, 874, 437, 850, 866,
+}
+
+// validCodePage returns true if cp can be passed to WithCodePage.
+func validCodePage(cp int) bool {
+	if cp == CodePageAuto || cp == CodePageUTF8 {
+		return true
+	}
+	_, ok := codePages[cp]
+	return ok
+}
+
+// decodeANSI decodes raw with the code page. CodePageAuto guesses the code
+// page of raw alone. Characters that are not in the code page become U+FFFD.
+func decodeANSI(raw []byte, cp int) string {
+	if isASCII(raw) {
+		return string(raw)
+	}
+	if cp == CodePageAuto {
+		cp = guessCodePage([][]byte{raw})
+	}
+	enc, ok := codePages[cp]
+	if !ok {
+		// UTF-8. Invalid bytes are replaced by the conversion.
+		return string([]rune(string(raw)))
+	}
+	str, err := enc.NewDecoder().Bytes(raw)
+	if err != nil {
+		return string(raw)
+	}
+	return string(str)
+}
+
+// encodeANSI is the reverse of decodeANSI. Characters that are not in the
+// code page are replaced with '?' like Windows does. CodePageAuto uses 1252.
+func encodeANSI(s string, cp int) []byte {
+	if isASCII([]byte(s)) || cp == CodePageUTF8 {
+		return []byte(s)
+	}
+	enc, ok := codePages[cp]
+	if !ok {
+		enc = charmap.Windows1252
+	}
+	var out []byte
+	for _, r := range s {
+		b, err := enc.NewEncoder().Bytes([]byte(string(r)))
+		if err != nil || r == utf8.RuneError {
+			b = []byte{'?'}
+		}
+		out = append(out, b...)
+	}
+	return out
+}
+
+// inCodePage returns true if r can be encoded in the code page. CodePageAuto
+// uses 1252 like encodeANSI.
+func inCodePage(r rune, cp int) bool {
+	return r == '?' || string(encodeANSI(string(r), cp)) != "?"
+}
+
+// ansiBytes returns the bytes of an ANSI string for writing. raw is returned
+// if s was decoded from it so the file does not change, otherwise s is
+// encoded with the code page.
+func ansiBytes(s string, raw []byte, cp int) []byte {
+	if raw != nil && decodeANSI(raw, cp) == s {
+		return raw
+	}
+	return encodeANSI(s, cp)
+}
+
+// nullANSI returns ansiBytes with a null terminator.
+func nullANSI(s string, raw []byte, cp int) []byte {
+	return append(ansiBytes(s, raw, cp), 0x00)
+}
+
+// readANSI returns the bytes until the first 0x00 and the decoded string.
+func readANSI(data []byte, cp int) (string, []byte) {
+	raw := []byte(readString(data))
+	return decodeANSI(raw, cp), raw
+}
+
+// isASCII returns true if all bytes are smaller than 0x80.
+func isASCII(b []byte) bool {
+	for _, c := range b {
+		if c >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// ansiPair is an ANSI string and the Unicode version of the same string from
+// the same file.
+type ansiPair struct {
+	raw     []byte
+	unicode string
+}
+
+// detectCodePage returns the first code page in detectOrder that decodes
+// every non-ASCII pair to its Unicode version. If no pair has non-ASCII
+// characters or no code page matches, the code page is guessed from samples.
+func detectCodePage(pairs []ansiPair, samples [][]byte) int {
+	var check []ansiPair
+	for _, p := range pairs {
+		if !isASCII(p.raw) && p.unicode != "" {
+			check = append(check, p)
+		}
+	}
+	if len(check) > 0 {
+		for _, cp := range detectOrder {
+			match := true
+			for _, p := range check {
+				if decodeANSI(p.raw, cp) != p.unicode {
+					match = false
+					break
+				}
+			}
+			if match {
+				return cp
+			}
+		}
+	}
+	return guessCodePage(samples)
+}
+
+// guessCodePage guesses the code page from the bytes of ANSI strings:
+//
+//  1. Only ASCII is 1252.
+//  2. Valid UTF-8 is UTF-8.
+//  3. Valid Shift-JIS with lead bytes in 0x81-0x9F (kana and most kanji) is
+//     code page 932. Trail bytes can be ASCII so this is checked first.
+//  4. High bytes mostly between ASCII characters (e.g. é in "Qualité") is
+//     1252.
+//  5. Only Cyrillic letters (0xC0-0xFF, Ё and ё) is 1251.
+//  6. Double-byte characters with lead bytes in 0xB0-0xC8 (Hangul) is 949,
+//     other lead bytes are 936.
+//
+// These are guesses, short strings can be wrong. Use WithCodePage if the
+// code page is known.
+func guessCodePage(samples [][]byte) int {
+	var high, isolated int
+	cyrillic, utf := true, true
+	for _, s := range samples {
+		if !utf8.Valid(s) {
+			utf = false
+		}
+		for i, c := range s {
+			if c < 0x80 {
+				continue
+			}
+			high++
+			if (i == 0 || s[i-1] < 0x80) && (i == len(s)-1 || s[i+1] < 0x80) {
+				isolated++
+			}
+			if c < 0xC0 && c != 0xA8 && c != 0xB8 {
+				cyrillic = false
+			}
+		}
+	}
+
+	switch {
+	case high == 0:
+		return 1252
+	case utf:
+		return CodePageUTF8
+	case shiftJIS(samples):
+		return 932
+	case isolated*2 > high:
+		return 1252
+	case cyrillic:
+		return 1251
+	case hangul(samples):
+		return 949
+	}
+	return 936
+}
+
+// shiftJIS returns true if all samples are valid Shift-JIS and at least one
+// lead byte is in 0x81-0x9F which is not used by GBK and EUC-KR letters.
+func shiftJIS(samples [][]byte) bool {
+	lead := false
+	for _, s := range samples {
+		for i := 0; i < len(s); i++ {
+			c := s[i]
+			// ASCII and half-width katakana are one byte.
+			if c < 0x80 || (c >= 0xA1 && c <= 0xDF) {
+				continue
+			}
+			// 0x80, 0xA0 and 0xF0-0xFF are not lead bytes.
+			if c == 0x80 || c == 0xA0 || c >= 0xF0 || i+1 == len(s) {
+				return false
+			}
+			t := s[i+1]
+			if t < 0x40 || t == 0x7F || t > 0xFC {
+				return false
+			}
+			if c <= 0x9F {
+				lead = true
+			}
+			i++
+		}
+	}
+	return lead
+}
+
+// hangul returns true if the lead bytes of all double-byte characters are in
+// the Hangul range of EUC-KR.
+func hangul(samples [][]byte) bool {
+	for _, s := range samples {
+		for i := 0; i < len(s); i++ {
+			if s[i] < 0x80 {
+				continue
+			}
+			if s[i] < 0xB0 || s[i] > 0xC8 {
+				return false
+			}
+			i++
+		}
+	}
+	return true
+}
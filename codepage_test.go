@@ -0,0 +1,122 @@
+package lnk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func Test_guessCodePage(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{"ascii", []byte(`C:\Windows`), 1252},
+		{"western", []byte("Qualit\xE9"), 1252},
+		{"utf8", []byte("Qualité"), CodePageUTF8},
+		{"cyrillic", encodeANSI(`C:\Users\Дмитрий`, 1251), 1251},
+		{"japanese", encodeANSI(`C:\デスクトップ\資料`, 932), 932},
+		{"chinese", encodeANSI(`D:\中文文档`, 936), 936},
+		{"korean", encodeANSI(`D:\한국어`, 949), 949},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := guessCodePage([][]byte{tt.data}); got != tt.want {
+				t.Errorf("guessCodePage() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_decodeANSI(t *testing.T) {
+	tests := []struct {
+		name string
+		str  string
+		cp   int
+	}{
+		{"1252", "Méthodologie", 1252},
+		{"1251", "Документы", 1251},
+		{"1250", "Dokumenty źródłowe", 1250},
+		{"932", "デスクトップ", 932},
+		{"936", "中文文档", 936},
+		{"949", "한국어", 949},
+		{"utf8", "中文文档", CodePageUTF8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := encodeANSI(tt.str, tt.cp)
+			if got := decodeANSI(raw, tt.cp); got != tt.str {
+				t.Errorf("decodeANSI() = %q, want %q", got, tt.str)
+			}
+			if got := ansiBytes(tt.str, raw, tt.cp); !bytes.Equal(got, raw) {
+				t.Errorf("ansiBytes() = %x, want %x", got, raw)
+			}
+		})
+	}
+
+	// Characters that are not in the code page are replaced.
+	if got := string(encodeANSI("Дa", 1252)); got != "?a" {
+		t.Errorf("encodeANSI() = %q, want %q", got, "?a")
+	}
+}
+
+func Test_detectCodePage(t *testing.T) {
+	// Both are valid in 1252, the Unicode version decides.
+	raw := encodeANSI("Документы", 1251)
+	pairs := []ansiPair{{raw, "Документы"}}
+	if got := detectCodePage(pairs, [][]byte{raw}); got != 1251 {
+		t.Errorf("detectCodePage() = %d, want 1251", got)
+	}
+	// No match falls back to guessing.
+	pairs = []ansiPair{{[]byte("Qualit\xE9"), "something else"}}
+	if got := detectCodePage(pairs, [][]byte{pairs[0].raw}); got != 1252 {
+		t.Errorf("detectCodePage() = %d, want 1252", got)
+	}
+}
+
+func TestWithCodePage(t *testing.T) {
+	data, err := ioutil.ReadFile("test/remote.file.xp.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		opts    []Option
+		wantCP  int
+		want    string
+		wantErr bool
+	}{
+		{"auto", nil, 1252, `Archives\Méthodologie WAS\Norme de développement JAVA.doc`, false},
+		{"1251", []Option{WithCodePage(1251)}, 1251, `Archives\Mйthodologie WAS\Norme de dйveloppement JAVA.doc`, false},
+		{"unsupported", []Option{WithCodePage(12345)}, 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Read(bytes.NewReader(data), uint64(len(data)), tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if f.CodePage != tt.wantCP {
+				t.Errorf("CodePage = %d, want %d", f.CodePage, tt.wantCP)
+			}
+			if got := f.LinkInfo.CommonPathSuffix; got != tt.want {
+				t.Errorf("CommonPathSuffix = %q, want %q", got, tt.want)
+			}
+			if got := string(f.LinkInfo.CommonPathSuffixRaw); got != "Archives\\M\xE9thodologie WAS\\Norme de d\xE9veloppement JAVA.doc" {
+				t.Errorf("CommonPathSuffixRaw = %q", got)
+			}
+			// The raw bytes are written back.
+			out, err := f.MarshalBinary()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, data) {
+				t.Errorf("MarshalBinary() does not match the file")
+			}
+		})
+	}
+}
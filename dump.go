@@ -0,0 +1,119 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DumpOptions changes the output of AnnotatedDump.
+type DumpOptions struct {
+	// Color adds ANSI colours, one per section. Unparsed bytes are red.
+	Color bool
+}
+
+// Labels of the bytes that do not belong to a field.
+const (
+	unparsedLabel = "(unparsed)"
+	trailingLabel = "(trailing data)"
+)
+
+// sectionColors are the ANSI colours of the sections.
+var sectionColors = map[string]string{
+	"ShellLinkHeader":  "34",
+	"LinkTargetIDList": "32",
+	"LinkInfo":         "33",
+	"StringData":       "35",
+	"ExtraData":        "36",
+	unparsedLabel:      "31",
+	trailingLabel:      "31",
+}
+
+// AnnotatedDump returns a hex dump of data, the contents of the file f was
+// parsed from. Each field starts a new line labelled with its offset,
+// structure and field name, e.g. "0x0014 ... ShellLinkHeader.LinkFlags".
+// Bytes that do not belong to a field are labelled "(unparsed)" and the bytes
+// after the ExtraData section "(trailing data)". If data is nil, the dump is
+// of f.MarshalBinary and an error is returned if f cannot be written or the
+// result cannot be parsed. The fields are located from the spans recorded by
+// Read.
+func (f LnkFile) AnnotatedDump(data []byte, opts DumpOptions) (string, error) {
+	if data == nil {
+		// Parse the written file to find the fields.
+		var err error
+		if data, err = f.MarshalBinary(); err != nil {
+			return "", fmt.Errorf("golnk.AnnotatedDump: %w", err)
+		}
+		if f, err = Read(bytes.NewReader(data), uint64(len(data)), Lenient()); err != nil {
+			return "", fmt.Errorf("golnk.AnnotatedDump: %w", err)
+		}
+	}
+
+	var sb strings.Builder
+	var pos int64
+	size := int64(len(data))
+	for _, fi := range f.fields() {
+		if fi.structure {
+			continue
+		}
+		if fi.Offset >= size {
+			break
+		}
+		if fi.Offset > pos {
+			writeField(&sb, data, Field{unparsedLabel, Span{pos, fi.Offset - pos}}, "", opts)
+		}
+		if fi.End() > size {
+			fi.Size = size - fi.Offset
+		}
+		writeField(&sb, data, fi.Field, fi.note, opts)
+		if end := fi.End(); end > pos {
+			pos = end
+		}
+	}
+	if pos < size {
+		writeField(&sb, data, Field{trailingLabel, Span{pos, size - pos}}, "", opts)
+	}
+	return sb.String(), nil
+}
+
+// writeField writes the bytes of a field, 16 per line. The name and note are
+// on the first line.
+func writeField(sb *strings.Builder, data []byte, fi Field, note string, opts DumpOptions) {
+	color := sectionColors[strings.SplitN(fi.Name, ".", 2)[0]]
+	for start := fi.Offset; start < fi.End() || start == fi.Offset; start += 16 {
+		end := start + 16
+		if end > fi.End() {
+			end = fi.End()
+		}
+		line := data[start:end]
+
+		var hexStr, ascii strings.Builder
+		for i, b := range line {
+			if i > 0 {
+				hexStr.WriteByte(' ')
+			}
+			fmt.Fprintf(&hexStr, "%02X", b)
+			if b >= 0x20 && b < 0x7F {
+				ascii.WriteByte(b)
+			} else {
+				ascii.WriteByte('.')
+			}
+		}
+		label := ""
+		if start == fi.Offset {
+			label = fi.Name
+			if note != "" {
+				label += " (" + note + ")"
+			}
+		}
+		text := strings.TrimRight(fmt.Sprintf("0x%04X  %-47s  %-16s  %s", start, hexStr.String(), ascii.String(), label), " ")
+		if opts.Color && color != "" {
+			text = "\x1b[" + color + "m" + text + "\x1b[0m"
+		}
+		sb.WriteString(text)
+		sb.WriteByte('\n')
+		if fi.Size == 0 {
+			break
+		}
+	}
+}
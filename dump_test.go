@@ -0,0 +1,54 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestAnnotatedDump(t *testing.T) {
+	for _, name := range samples {
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := Read(bytes.NewReader(data), uint64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Every byte of the samples belongs to a field.
+			got, err := f.AnnotatedDump(append(data, 'M', 'Z'), DumpOptions{})
+			if err != nil {
+				t.Fatalf("AnnotatedDump() error = %v", err)
+			}
+			if strings.Contains(got, unparsedLabel) {
+				t.Errorf("AnnotatedDump() has unparsed bytes:\n%s", got)
+			}
+			for _, want := range []string{
+				"ShellLinkHeader.LinkFlags\n",
+				"ExtraData.TerminalBlock\n",
+				fmt.Sprintf("0x%04X  4D 5A", len(data)),
+				"MZ                (trailing data)\n",
+			} {
+				if !strings.Contains(got, want) {
+					t.Errorf("AnnotatedDump() does not contain %q:\n%s", want, got)
+				}
+			}
+			if got, err := f.AnnotatedDump(nil, DumpOptions{Color: true}); err != nil || !strings.Contains(got, "\x1b[34m0x0000") {
+				t.Errorf("AnnotatedDump() error = %v, has no colours:\n%s", err, got)
+			}
+		})
+	}
+
+	// The StringData of the file cannot be written.
+	f, err := NewBuilder().Target(`C:\a.exe`).Description(strings.Repeat("a", 0x10000)).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.AnnotatedDump(nil, DumpOptions{}); err == nil {
+		t.Errorf("AnnotatedDump() error = nil, want the MarshalBinary error")
+	}
+}
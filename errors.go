@@ -0,0 +1,82 @@
+package lnk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Sentinel errors wrapped by ParseError. Use errors.Is to check them.
+var (
+	// ErrBadMagic means HeaderSize is not 0x4C, the file is not an lnk file.
+	ErrBadMagic = errors.New("invalid header size")
+	// ErrBadCLSID means LinkCLSID is not 00021401-0000-0000-C000-000000000046.
+	ErrBadCLSID = errors.New("invalid LinkCLSID")
+	// ErrTruncated means the data ended before the end of a field.
+	ErrTruncated = errors.New("truncated data")
+	// ErrSizeExceeded means a size field is larger than the maximum size.
+	ErrSizeExceeded = errors.New("size exceeds the maximum")
+	// ErrBadOffset means an offset points outside of its structure.
+	ErrBadOffset = errors.New("offset out of bounds")
+	// ErrBadSize means a size field is smaller than its structure.
+	ErrBadSize = errors.New("invalid size")
+	// ErrLimitExceeded means parsing the file needs more than the Limits.
+	ErrLimitExceeded = errors.New("limit exceeded")
+	// ErrBadString means a UTF-16 string has an unpaired surrogate or an odd
+	// number of bytes. Only used in Warnings, the string is kept with U+FFFD.
+	ErrBadString = errors.New("invalid UTF-16 string")
+)
+
+// ParseError is returned when a section cannot be parsed.
+type ParseError struct {
+	// Section is the structure name from the specification, e.g.
+	// ShellLinkHeader, LinkTargetIDList, LinkInfo, VolumeID,
+	// CommonNetworkRelativeLink, StringData or ExtraData.
+	Section string
+	// Field is the field that could not be read.
+	Field string
+	// Offset of the field. From the start of the file if returned by Read and
+	// File, otherwise from the start of the section passed to the function.
+	Offset int64
+	// Err is the cause, usually one of the sentinel errors.
+	Err error
+}
+
+// Error returns the section, field, offset and cause.
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("golnk: %s.%s at offset 0x%X - %s", e.Section, e.Field, e.Offset, e.Err.Error())
+}
+
+// Unwrap returns the cause.
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// parseError returns a *ParseError. io.EOF and io.ErrUnexpectedEOF become
+// ErrTruncated.
+func parseError(section, field string, offset int64, err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		err = ErrTruncated
+	}
+	return &ParseError{Section: section, Field: field, Offset: offset, Err: err}
+}
+
+// addOffset adds base to the offset of the ParseError in err, if any. Used
+// when a nested structure or section was parsed from its own start.
+func addOffset(err error, base int64) error {
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.Offset += base
+	}
+	return err
+}
+
+// countingReader counts the bytes read to find the offset of each section.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,193 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseError(t *testing.T) {
+	data, err := ioutil.ReadFile("test/test.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// LinkInfo starts after the header and the 0x236 byte IDList.
+	const linkInfo = 0x4C + 2 + 0x236
+
+	tests := []struct {
+		name    string
+		modify  func(b []byte) []byte
+		want    error
+		section string
+		field   string
+		offset  int64
+	}{
+		{"bad-magic", func(b []byte) []byte { b[0] = 0x4D; return b }, ErrBadMagic, "ShellLinkHeader", "HeaderSize", 0},
+		{"bad-clsid", func(b []byte) []byte { b[4] = 0xFF; return b }, ErrBadCLSID, "ShellLinkHeader", "LinkCLSID", 0x04},
+		{"empty", func(b []byte) []byte { return nil }, ErrTruncated, "ShellLinkHeader", "HeaderSize", 0},
+		{"truncated-header", func(b []byte) []byte { return b[:0x30] }, ErrTruncated, "ShellLinkHeader", "WriteTime", 0x2C},
+		{"truncated-idlist", func(b []byte) []byte { return b[:0x60] }, ErrTruncated, "LinkTargetIDList", "ItemID.Data", 0x50},
+		{"size-exceeded", func(b []byte) []byte { b[linkInfo+2] = 0xFF; return b }, ErrSizeExceeded, "LinkInfo", "LinkInfoSize", linkInfo},
+		{"truncated-extradata", func(b []byte) []byte { return b[:len(b)-2] }, ErrTruncated, "ExtraData", "BlockSize", int64(len(data) - 4)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.modify(append([]byte(nil), data...))
+			_, err := Read(bytes.NewReader(b), uint64(len(b)))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Read() error = %v, want %v", err, tt.want)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Read() error = %v, want a *ParseError", err)
+			}
+			if pe.Section != tt.section || pe.Field != tt.field || pe.Offset != tt.offset {
+				t.Errorf("ParseError = %s.%s at 0x%X, want %s.%s at 0x%X",
+					pe.Section, pe.Field, pe.Offset, tt.section, tt.field, tt.offset)
+			}
+		})
+	}
+}
+
+func TestBadString(t *testing.T) {
+	f, err := NewBuilder().Target(`C:\Tэst\app.exe`).WorkingDir(`C:\Tэst`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := f.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err = Read(bytes.NewReader(data), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spans := make(map[string]Span)
+	for _, fi := range f.Offsets() {
+		spans[fi.Name] = fi.Span
+	}
+	// Replace the first character of the strings with an unpaired low
+	// surrogate.
+	path := spans["LinkInfo.LocalBasePathUnicode"].Offset
+	dir := spans["StringData.WorkingDir"].Offset
+	data[path], data[path+1] = 0x00, 0xDC
+	data[dir], data[dir+1] = 0x00, 0xDC
+
+	for _, opts := range [][]Option{nil, {Lenient()}} {
+		f, err := Read(bytes.NewReader(data), uint64(len(data)), opts...)
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+		if f.LinkInfo.LocalBasePathUnicode != "\uFFFD:\\Tэst\\app.exe" || f.StringData.WorkingDir != "\uFFFD:\\Tэst" {
+			t.Errorf("Read() = %q and %q, want the strings with U+FFFD",
+				f.LinkInfo.LocalBasePathUnicode, f.StringData.WorkingDir)
+		}
+		want := []ParseWarning{
+			{Section: "LinkInfo", Field: "LocalBasePathUnicode", Offset: path},
+			{Section: "StringData", Field: "WorkingDir", Offset: dir - 2},
+		}
+		if len(f.Warnings) != len(want) {
+			t.Fatalf("Read() Warnings = %v, want %d", f.Warnings, len(want))
+		}
+		for i, w := range f.Warnings {
+			if w.Section != want[i].Section || w.Field != want[i].Field || w.Offset != want[i].Offset ||
+				!errors.Is(w.Err, ErrBadString) {
+				t.Errorf("Warnings[%d] = %s, want %s.%s at 0x%X", i, w, want[i].Section, want[i].Field, want[i].Offset)
+			}
+		}
+	}
+}
+
+// TestParseErrorNetwork checks that the errors of the
+// CommonNetworkRelativeLink are returned with the offset in the file.
+func TestParseErrorNetwork(t *testing.T) {
+	data, err := ioutil.ReadFile("test/remote.file.xp.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The CommonNetworkRelativeLink is at 0x340, make it larger than the file.
+	data[0x341] = 0x10
+
+	_, err = Read(bytes.NewReader(data), uint64(len(data)))
+	var pe *ParseError
+	if !errors.Is(err, ErrSizeExceeded) || !errors.As(err, &pe) {
+		t.Fatalf("Read() error = %v, want a *ParseError with ErrSizeExceeded", err)
+	}
+	if pe.Section != "CommonNetworkRelativeLink" || pe.Field != "CommonNetworkRelativeLinkSize" || pe.Offset != 0x340 {
+		t.Errorf("ParseError = %s.%s at 0x%X, want CommonNetworkRelativeLink.CommonNetworkRelativeLinkSize at 0x340",
+			pe.Section, pe.Field, pe.Offset)
+	}
+}
+
+// TestBadStringNested checks that the blocks and shell items with invalid
+// UTF-16 are kept and the strings are reported as warnings.
+func TestBadStringNested(t *testing.T) {
+	// A Serialized Property Store with a VT_LPWSTR value.
+	value := []byte{
+		0x0C, 0x00, 0x00, 0x00, 0x00, // ID and reserved byte.
+		0x1F, 0x00, 0x00, 0x00, // VT_LPWSTR and padding.
+		0x02, 0x00, 0x00, 0x00, 0x00, 0xDC, 0x00, 0x00, // Length and the string.
+	}
+	store := append(uint32Byte(propertyStoreVersion), make([]byte, 16)...)
+	store = append(store, uint32Byte(uint32(len(value)+4))...)
+	store = append(store, value...)
+	store = append(store, uint32Byte(0)...)
+	propStore := append(uint32Byte(uint32(len(store)+4)), store...)
+	propStore = append(propStore, uint32Byte(0)...)
+
+	tests := []struct {
+		name  string
+		block []byte
+		field string
+		// Offset of the string in the block data.
+		offset int64
+	}{
+		{"console", newTestBlock(consoleDataSize).put16(0x24, 0xDC00).block(0xA0000002),
+			"ConsoleDataBlock.FaceName", 0x24},
+		{"environment", newTestBlock(environmentDataSize).put16(0x104, 0xDC00).block(0xA0000001),
+			"EnvironmentVariableDataBlock.TargetUnicode", 0x104},
+		{"darwin", newTestBlock(darwinDataSize).put16(0x104, 0xDC00).block(0xA0000006),
+			"DarwinDataBlock.DarwinDataUnicode", 0x104},
+		{"shim", newTestBlock(shimDataMinSize).put16(0, 0xDC00).block(0xA0000008),
+			"ShimDataBlock.LayerName", 0},
+		{"property-store", testBlock(propStore).block(0xA0000009),
+			"PropertyStoreDataBlock.Value", 4 + 20 + 4 + 13},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newBudget(DefaultLimits)
+			extra, err := dataBlock(bytes.NewReader(extraData(tt.block)), b)
+			if err != nil {
+				t.Fatalf("dataBlock() error = %v", err)
+			}
+			if extra.Blocks[0].Parsed == nil {
+				t.Errorf("dataBlock() did not parse the block")
+			}
+			if len(b.warnings) != 1 || b.warnings[0].Field != tt.field ||
+				b.warnings[0].Offset != 8+tt.offset || !errors.Is(b.warnings[0].Err, ErrBadString) {
+				t.Errorf("warnings = %v, want %s at 0x%X", b.warnings, tt.field, 8+tt.offset)
+			}
+		})
+	}
+
+	t.Run("shell-item", func(t *testing.T) {
+		// A file entry with a Unicode name.
+		item := []byte{0x34, 0x00, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x00, 0xDC, 0x00, 0x00}
+		list := append(uint16Byte(uint16(len(item)+4)), uint16Byte(uint16(len(item)+2))...)
+		list = append(list, item...)
+		list = append(list, 0x00, 0x00)
+
+		b := newBudget(DefaultLimits)
+		li, err := linkTarget(bytes.NewReader(list), b)
+		if err != nil {
+			t.Fatalf("linkTarget() error = %v", err)
+		}
+		if fe, ok := li.List.ItemIDList[0].Item.(FileEntryItem); !ok || fe.PrimaryName != "\uFFFD" {
+			t.Errorf("linkTarget() item = %+v, want a FileEntryItem with U+FFFD", li.List.ItemIDList[0].Item)
+		}
+		if len(b.warnings) != 1 || b.warnings[0].Field != "ItemID[0]" || b.warnings[0].Offset != 2 {
+			t.Errorf("warnings = %v, want ItemID[0] at 0x2", b.warnings)
+		}
+	})
+}
@@ -1,11 +1,14 @@
 package lnk
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/olekukonko/tablewriter"
 )
 
 // ExtraDataSection represents section 2.5 of the specification.
@@ -14,6 +17,8 @@ type ExtraDataSection struct {
 	// Terminal block at the end of the ExtraData section.
 	// Value must be smaller than 0x04.
 	TerminalBlock uint32
+	// Span is the location of the section including the TerminalBlock.
+	Span Span
 }
 
 /*
@@ -31,31 +36,87 @@ type ExtraDataBlock struct {
 	Signature uint32
 	Type      string
 	Data      []byte
+	// Parsed contains the typed block (e.g. TrackerDataBlock) if the block
+	// type has a parser and Data was parsed successfully, otherwise nil.
+	// MarshalBinary writes Data and ignores Parsed.
+	Parsed fmt.Stringer
+	// Span is the location of the block.
+	Span Span
+}
+
+// blockParsers maps block signatures to the functions that parse their data.
+// Invalid UTF-16 strings are added to the warnings in b with offsets from the
+// start of data.
+var blockParsers = map[uint32]func(data []byte, b *budget) (fmt.Stringer, error){
+	0xA0000001: func(data []byte, b *budget) (fmt.Stringer, error) { return environment(data, b) },
+	0xA0000002: func(data []byte, b *budget) (fmt.Stringer, error) { return console(data, b) },
+	0xA0000003: func(data []byte, _ *budget) (fmt.Stringer, error) { return Tracker(data) },
+	0xA0000004: func(data []byte, _ *budget) (fmt.Stringer, error) { return ConsoleFE(data) },
+	0xA0000006: func(data []byte, b *budget) (fmt.Stringer, error) { return darwin(data, b) },
+	0xA0000007: func(data []byte, b *budget) (fmt.Stringer, error) { return iconEnvironment(data, b) },
+	0xA0000005: func(data []byte, _ *budget) (fmt.Stringer, error) { return SpecialFolder(data) },
+	0xA0000008: func(data []byte, b *budget) (fmt.Stringer, error) { return shim(data, b) },
+	0xA0000009: func(data []byte, b *budget) (fmt.Stringer, error) { return propertyStoreBlock(data, b) },
+	0xA000000B: func(data []byte, _ *budget) (fmt.Stringer, error) { return KnownFolder(data) },
+}
+
+// parseBlock returns the typed block for the signature, nil if the block type
+// has no parser or cannot be parsed. Data is still available in
+// ExtraDataBlock.Data in that case.
+func parseBlock(sig uint32, data []byte, b *budget) fmt.Stringer {
+	parse, exists := blockParsers[sig]
+	if !exists {
+		return nil
+	}
+	block, err := parse(data, b)
+	if err != nil {
+		return nil
+	}
+	return block
 }
 
-// DataBlock reads and populates an ExtraData.
+// DataBlock reads and populates an ExtraData. Errors are *ParseError.
+// DefaultLimits are used.
 func DataBlock(r io.Reader) (extra ExtraDataSection, err error) {
+	return dataBlock(r, newBudget(DefaultLimits))
+}
 
-	var db ExtraDataBlock
+// dataBlock parses the ExtraData blocks within the limits in b.
+func dataBlock(r io.Reader, b *budget) (extra ExtraDataSection, err error) {
+
+	// Offset of the current block.
+	var offset int64
 	for {
+		var db ExtraDataBlock
 		// Read size.
 		var size uint32
 		err = binary.Read(r, binary.LittleEndian, &size)
 		if err != nil {
-			return extra, fmt.Errorf("golnk.readDataBlock: read size - %s", err.Error())
+			return extra, parseError("ExtraData", "BlockSize", offset, err)
 		}
 		// fmt.Println("Size", size)
 		// Have we reached the TerminalBlock?
 		if size < 0x04 {
 			extra.TerminalBlock = size
+			extra.Span = Span{Size: offset + 4}
 			break
 		}
+		// The size includes itself and the signature.
+		if size < 0x08 {
+			return extra, parseError("ExtraData", "BlockSize", offset,
+				fmt.Errorf("%w - got %d, want at least 8", ErrBadSize, size))
+		}
+		// Check the limits before the block data is allocated.
+		if err = b.block(uint64(size - 8)); err != nil {
+			return extra, parseError("ExtraData", "BlockSize", offset, err)
+		}
 		db.Size = size
+		db.Span = Span{Offset: offset, Size: int64(size)}
 
 		// Read block's signature.
 		err = binary.Read(r, binary.LittleEndian, &db.Signature)
 		if err != nil {
-			return extra, fmt.Errorf("golnk.readDataBlock: read signature - %s", err.Error())
+			return extra, parseError("ExtraData", "BlockSignature", offset+4, err)
 		}
 		// fmt.Println("Signature", hex.EncodeToString(uint32Byte(db.Signature)))
 		db.Type = blockSignature(db.Signature)
@@ -65,15 +126,55 @@ func DataBlock(r io.Reader) (extra ExtraDataSection, err error) {
 		data := make([]byte, db.Size-8)
 		err = binary.Read(r, binary.LittleEndian, &data)
 		if err != nil {
-			return extra, fmt.Errorf("golnk.readDataBlock: read data - %s", err.Error())
+			return extra, parseError("ExtraData", db.Type, offset+8, err)
 		}
 		db.Data = data
+		n := len(b.warnings)
+		db.Parsed = parseBlock(db.Signature, data, b)
+		b.shiftWarnings(n, offset+8)
 		// fmt.Println(hex.Dump(data))
 		extra.Blocks = append(extra.Blocks, db)
+		offset += int64(db.Size)
 	}
 	return extra, nil
 }
 
+// shift adds base to the spans of the section and its blocks.
+func (e *ExtraDataSection) shift(base int64) {
+	e.Span.shift(base)
+	for i := range e.Blocks {
+		e.Blocks[i].Span.shift(base)
+	}
+}
+
+// MarshalBinary returns the ExtraData section as it appears on disk. The size
+// of each block is calculated from Data and the section ends with
+// TerminalBlock. The typed blocks in Parsed are not written, changes to them
+// are lost. Change Data to modify a block.
+func (e ExtraDataSection) MarshalBinary() ([]byte, error) {
+	if e.TerminalBlock >= 0x04 {
+		return nil, fmt.Errorf("golnk.ExtraDataSection.MarshalBinary: invalid TerminalBlock - got %d, want < 4", e.TerminalBlock)
+	}
+	var buf bytes.Buffer
+	for _, db := range e.Blocks {
+		buf.Write(uint32Byte(uint32(len(db.Data) + 8)))
+		buf.Write(uint32Byte(db.Signature))
+		buf.Write(db.Data)
+	}
+	buf.Write(uint32Byte(e.TerminalBlock))
+	return buf.Bytes(), nil
+}
+
+// Block returns the first block with the signature.
+func (e ExtraDataSection) Block(sig uint32) (ExtraDataBlock, bool) {
+	for _, b := range e.Blocks {
+		if b.Signature == sig {
+			return b, true
+		}
+	}
+	return ExtraDataBlock{}, false
+}
+
 // blockSignature returns the block type based on signature.
 func blockSignature(sig uint32) string {
 	signatureMap := map[uint32]string{
@@ -103,6 +204,9 @@ func (e ExtraDataSection) String() string {
 		sb.WriteString(fmt.Sprintf("Size: %s\n", uint32TableStr(b.Size)))
 		sb.WriteString(fmt.Sprintf("Signature: %s\n", uint32StrHex(b.Signature)))
 		sb.WriteString(fmt.Sprintf("Type: %s\n", b.Type))
+		if b.Parsed != nil {
+			sb.WriteString(b.Parsed.String())
+		}
 		sb.WriteString("Dump\n")
 		sb.WriteString(b.Dump())
 		sb.WriteString("-------------------------\n")
@@ -114,3 +218,18 @@ func (e ExtraDataSection) String() string {
 func (db ExtraDataBlock) Dump() string {
 	return hex.Dump(db.Data)
 }
+
+// blockTable prints the name and value rows of a typed block in a table.
+func blockTable(name string, rows [][]string) string {
+	var sb strings.Builder
+
+	table := tablewriter.NewWriter(&sb)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{name, "Value"})
+	table.AppendBulk(rows)
+
+	table.Render()
+	return sb.String()
+}
@@ -0,0 +1,270 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// ConsoleDataBlock (section 2.5.1) contains the display settings of the
+// console window if the target is a console application, e.g. cmd.exe or
+// powershell.exe. Signature 0xA0000002.
+type ConsoleDataBlock struct {
+	// FillAttributes are the foreground and background colours of the text,
+	// e.g. FOREGROUND_BLUE. Originally a uint16.
+	FillAttributes FlagMap
+	// PopupFillAttributes are the colours of the popup text.
+	PopupFillAttributes FlagMap
+
+	// Size of the screen buffer in characters.
+	ScreenBufferSizeX int16
+	ScreenBufferSizeY int16
+
+	// Size of the window in characters.
+	WindowSizeX int16
+	WindowSizeY int16
+
+	// Position of the window in pixels.
+	WindowOriginX int16
+	WindowOriginY int16
+
+	// Unused, should be zero.
+	Unused1 uint32
+	Unused2 uint32
+
+	// FontSize is the height of the font in the high 16 bits and the width in
+	// the low 16 bits. The width is zero for TrueType fonts.
+	FontSize uint32
+
+	// FontFamily is the family and pitch of the font, e.g.
+	// "FF_MODERN | TMPF_VECTOR | TMPF_TRUETYPE". Originally a uint32.
+	FontFamily string
+
+	// FontWeight is 700 or more for bold fonts.
+	FontWeight uint32
+
+	// FaceName is the name of the font. 64 bytes on disk, UTF-16.
+	FaceName string
+
+	// CursorSize is the size of the cursor in percent of the character cell:
+	// 25 or less is small, 26 to 50 medium and 51 to 100 large.
+	CursorSize uint32
+
+	// The window is full screen.
+	FullScreen bool
+	// Text can be selected with the mouse.
+	QuickEdit bool
+	// Typed text is inserted instead of overwriting.
+	InsertMode bool
+	// The window position is chosen by the system and WindowOrigin is ignored.
+	AutoPosition bool
+
+	// HistoryBufferSize is the number of commands in each history buffer.
+	HistoryBufferSize uint32
+	// NumberOfHistoryBuffers is the number of history buffers.
+	NumberOfHistoryBuffers uint32
+	// HistoryNoDup removes duplicate commands from the history.
+	HistoryNoDup bool
+
+	// ColorTable is the palette used by the fill attributes.
+	ColorTable [16]RGB
+}
+
+// RGB is a colour from a COLORREF (0x00BBGGRR).
+type RGB struct {
+	R, G, B uint8
+}
+
+// String returns the colour in #RRGGBB format.
+func (c RGB) String() string {
+	return fmt.Sprintf("#%02X%02X%02X", c.R, c.G, c.B)
+}
+
+// consoleDataSize is the size of ConsoleDataBlock after the signature.
+const consoleDataSize = 0xC4
+
+// fillAttributesFlags are the bits of FillAttributes and PopupFillAttributes.
+var fillAttributesFlags = []string{
+	"FOREGROUND_BLUE",      // bit00
+	"FOREGROUND_GREEN",     // bit01
+	"FOREGROUND_RED",       // bit02
+	"FOREGROUND_INTENSITY", // bit03
+	"BACKGROUND_BLUE",      // bit04
+	"BACKGROUND_GREEN",     // bit05
+	"BACKGROUND_RED",       // bit06
+	"BACKGROUND_INTENSITY", // bit07
+}
+
+// fontFamilies are the font families in the high nibble of the low byte of
+// FontFamily.
+var fontFamilies = map[uint32]string{
+	0x0000: "FF_DONTCARE",
+	0x0010: "FF_ROMAN",
+	0x0020: "FF_SWISS",
+	0x0030: "FF_MODERN",
+	0x0040: "FF_SCRIPT",
+	0x0050: "FF_DECORATIVE",
+}
+
+// fontPitchFlags are the pitch bits in the low nibble of FontFamily.
+var fontPitchFlags = []string{
+	"TMPF_FIXED_PITCH", // bit00
+	"TMPF_VECTOR",      // bit01
+	"TMPF_TRUETYPE",    // bit02
+	"TMPF_DEVICE",      // bit03
+}
+
+// fontFamily returns the family and pitch flags of f separated by " | ".
+func fontFamily(f uint32) string {
+	family, ok := fontFamilies[f&0xF0]
+	if !ok {
+		family = uint32StrHex(f & 0xF0)
+	}
+	parts := []string{family}
+	pitch := matchFlag(f&0x0F, fontPitchFlags)
+	for _, p := range fontPitchFlags {
+		if pitch[p] {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Console parses the data of a ConsoleDataBlock. data is ExtraDataBlock.Data
+// (everything after the signature).
+func Console(data []byte) (c ConsoleDataBlock, err error) {
+	return console(data, newBudget(DefaultLimits))
+}
+
+// console parses a ConsoleDataBlock and adds an invalid FaceName to the
+// warnings in b.
+func console(data []byte, b *budget) (c ConsoleDataBlock, err error) {
+	if len(data) < consoleDataSize {
+		return c, fmt.Errorf("golnk.Console: invalid size - got %d bytes, want %d", len(data), consoleDataSize)
+	}
+	r := bytes.NewReader(data)
+
+	// All fields are fixed size and we have checked the size, these reads
+	// cannot fail.
+	var fill, popup uint16
+	binary.Read(r, binary.LittleEndian, &fill)
+	binary.Read(r, binary.LittleEndian, &popup)
+	c.FillAttributes = matchFlag(uint32(fill), fillAttributesFlags)
+	c.PopupFillAttributes = matchFlag(uint32(popup), fillAttributesFlags)
+
+	binary.Read(r, binary.LittleEndian, &c.ScreenBufferSizeX)
+	binary.Read(r, binary.LittleEndian, &c.ScreenBufferSizeY)
+	binary.Read(r, binary.LittleEndian, &c.WindowSizeX)
+	binary.Read(r, binary.LittleEndian, &c.WindowSizeY)
+	binary.Read(r, binary.LittleEndian, &c.WindowOriginX)
+	binary.Read(r, binary.LittleEndian, &c.WindowOriginY)
+	binary.Read(r, binary.LittleEndian, &c.Unused1)
+	binary.Read(r, binary.LittleEndian, &c.Unused2)
+	binary.Read(r, binary.LittleEndian, &c.FontSize)
+
+	var family uint32
+	binary.Read(r, binary.LittleEndian, &family)
+	c.FontFamily = fontFamily(family)
+	binary.Read(r, binary.LittleEndian, &c.FontWeight)
+
+	var faceName [64]byte
+	binary.Read(r, binary.LittleEndian, &faceName)
+	c.FaceName, err = b.utf16("ExtraData", "ConsoleDataBlock.FaceName", 0x24, faceName[:])
+	if err != nil {
+		return c, fmt.Errorf("golnk.Console: %w", err)
+	}
+
+	binary.Read(r, binary.LittleEndian, &c.CursorSize)
+
+	var fullScreen, quickEdit, insertMode, autoPosition uint32
+	binary.Read(r, binary.LittleEndian, &fullScreen)
+	binary.Read(r, binary.LittleEndian, &quickEdit)
+	binary.Read(r, binary.LittleEndian, &insertMode)
+	binary.Read(r, binary.LittleEndian, &autoPosition)
+	c.FullScreen = fullScreen != 0
+	c.QuickEdit = quickEdit != 0
+	c.InsertMode = insertMode != 0
+	c.AutoPosition = autoPosition != 0
+
+	binary.Read(r, binary.LittleEndian, &c.HistoryBufferSize)
+	binary.Read(r, binary.LittleEndian, &c.NumberOfHistoryBuffers)
+	var noDup uint32
+	binary.Read(r, binary.LittleEndian, &noDup)
+	c.HistoryNoDup = noDup != 0
+
+	for i := range c.ColorTable {
+		var color uint32
+		binary.Read(r, binary.LittleEndian, &color)
+		c.ColorTable[i] = RGB{uint8(color), uint8(color >> 8), uint8(color >> 16)}
+	}
+	return c, nil
+}
+
+// flagsStr returns the set flags in order, one per line.
+func flagsStr(flags FlagMap, names []string) string {
+	var set []string
+	for _, n := range names {
+		if flags[n] {
+			set = append(set, n)
+		}
+	}
+	return strings.Join(set, "\n")
+}
+
+// String prints the ConsoleDataBlock in a table.
+func (c ConsoleDataBlock) String() string {
+	rows := [][]string{
+		{"FillAttributes", flagsStr(c.FillAttributes, fillAttributesFlags)},
+		{"PopupFillAttributes", flagsStr(c.PopupFillAttributes, fillAttributesFlags)},
+		{"ScreenBufferSize", fmt.Sprintf("%d x %d", c.ScreenBufferSizeX, c.ScreenBufferSizeY)},
+		{"WindowSize", fmt.Sprintf("%d x %d", c.WindowSizeX, c.WindowSizeY)},
+		{"WindowOrigin", fmt.Sprintf("%d, %d", c.WindowOriginX, c.WindowOriginY)},
+		{"FontSize", uint32StrHex(c.FontSize)},
+		{"FontHeight", uint32Str(c.FontSize >> 16)},
+		{"FontWidth", uint32Str(c.FontSize & 0xFFFF)},
+		{"FontFamily", c.FontFamily},
+		{"FontWeight", uint32Str(c.FontWeight)},
+		{"FaceName", c.FaceName},
+		{"CursorSize", uint32Str(c.CursorSize)},
+		{"FullScreen", fmt.Sprint(c.FullScreen)},
+		{"QuickEdit", fmt.Sprint(c.QuickEdit)},
+		{"InsertMode", fmt.Sprint(c.InsertMode)},
+		{"AutoPosition", fmt.Sprint(c.AutoPosition)},
+		{"HistoryBufferSize", uint32Str(c.HistoryBufferSize)},
+		{"NumberOfHistoryBuffers", uint32Str(c.NumberOfHistoryBuffers)},
+		{"HistoryNoDup", fmt.Sprint(c.HistoryNoDup)},
+	}
+	for i, color := range c.ColorTable {
+		rows = append(rows, []string{fmt.Sprintf("ColorTable[%d]", i), color.String()})
+	}
+
+	return blockTable("ConsoleDataBlock", rows)
+}
+
+// ConsoleFEDataBlock (section 2.5.2) contains the code page of the console
+// window. Signature 0xA0000004.
+type ConsoleFEDataBlock struct {
+	// CodePage is the code page used to display text, e.g. 437 or 65001.
+	CodePage uint32
+}
+
+// consoleFEDataSize is the size of ConsoleFEDataBlock after the signature.
+const consoleFEDataSize = 0x04
+
+// ConsoleFE parses the data of a ConsoleFEDataBlock. data is
+// ExtraDataBlock.Data (everything after the signature).
+func ConsoleFE(data []byte) (c ConsoleFEDataBlock, err error) {
+	if len(data) < consoleFEDataSize {
+		return c, fmt.Errorf("golnk.ConsoleFE: invalid size - got %d bytes, want %d", len(data), consoleFEDataSize)
+	}
+	c.CodePage = binary.LittleEndian.Uint32(data)
+	return c, nil
+}
+
+// String prints the ConsoleFEDataBlock in a table.
+func (c ConsoleFEDataBlock) String() string {
+	return blockTable("ConsoleFEDataBlock", [][]string{
+		{"CodePage", uint32Str(c.CodePage)},
+	})
+}
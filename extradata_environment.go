@@ -0,0 +1,161 @@
+package lnk
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// EnvironmentVariableDataBlock (section 2.5.4) contains the path of the
+// target with environment variables, e.g. %windir%\system32\cmd.exe. It's
+// used if HasExpString is set. Signature 0xA0000001.
+type EnvironmentVariableDataBlock struct {
+	// TargetAnsi is the path in ANSI. 260 bytes on disk, null-terminated.
+	TargetAnsi string
+	// TargetAnsiRaw is TargetAnsi before decoding with the code page.
+	TargetAnsiRaw []byte
+	// TargetUnicode is the path in UTF-16. 520 bytes on disk,
+	// null-terminated. Windows uses it instead of TargetAnsi if it's not empty.
+	TargetUnicode string
+
+	// Code page of TargetAnsi, used by Mismatch.
+	codePage int
+}
+
+// IconEnvironmentDataBlock (section 2.5.5) contains the path of the icon with
+// environment variables. It's used if HasExpIcon is set. Signature
+// 0xA0000007.
+type IconEnvironmentDataBlock struct {
+	// TargetAnsi is the path in ANSI. 260 bytes on disk, null-terminated.
+	TargetAnsi string
+	// TargetAnsiRaw is TargetAnsi before decoding with the code page.
+	TargetAnsiRaw []byte
+	// TargetUnicode is the path in UTF-16. 520 bytes on disk,
+	// null-terminated.
+	TargetUnicode string
+
+	// Code page of TargetAnsi, used by Mismatch.
+	codePage int
+}
+
+// environmentDataSize is the size of EnvironmentVariableDataBlock and
+// IconEnvironmentDataBlock after the signature.
+const environmentDataSize = 0x30C
+
+// readEnvironment returns the ANSI and Unicode paths of the block. The ANSI
+// path is decoded with CodePageAuto, Read decodes it again with the code page
+// of the file.
+func readEnvironment(block string, data []byte, b *budget) (ansi string, raw []byte, unicode string, err error) {
+	if len(data) < environmentDataSize {
+		return "", nil, "", fmt.Errorf("invalid size - got %d bytes, want %d", len(data), environmentDataSize)
+	}
+	ansi, raw = readANSI(data[:260], CodePageAuto)
+	unicode, err = b.utf16("ExtraData", block+".TargetUnicode", 0x104, data[260:780])
+	return ansi, raw, unicode, err
+}
+
+// Environment parses the data of an EnvironmentVariableDataBlock. data is
+// ExtraDataBlock.Data (everything after the signature). The ANSI path is
+// decoded with CodePageAuto, Read decodes it again with the code page of the
+// file.
+func Environment(data []byte) (e EnvironmentVariableDataBlock, err error) {
+	return environment(data, newBudget(DefaultLimits))
+}
+
+// environment parses an EnvironmentVariableDataBlock and adds invalid strings
+// to the warnings in b.
+func environment(data []byte, b *budget) (e EnvironmentVariableDataBlock, err error) {
+	e.TargetAnsi, e.TargetAnsiRaw, e.TargetUnicode, err = readEnvironment("EnvironmentVariableDataBlock", data, b)
+	if err != nil {
+		return e, fmt.Errorf("golnk.Environment: %w", err)
+	}
+	return e, nil
+}
+
+// IconEnvironment parses the data of an IconEnvironmentDataBlock. data is
+// ExtraDataBlock.Data (everything after the signature). The ANSI path is
+// decoded like in Environment.
+func IconEnvironment(data []byte) (e IconEnvironmentDataBlock, err error) {
+	return iconEnvironment(data, newBudget(DefaultLimits))
+}
+
+// iconEnvironment parses an IconEnvironmentDataBlock and adds invalid strings
+// to the warnings in b.
+func iconEnvironment(data []byte, b *budget) (e IconEnvironmentDataBlock, err error) {
+	e.TargetAnsi, e.TargetAnsiRaw, e.TargetUnicode, err = readEnvironment("IconEnvironmentDataBlock", data, b)
+	if err != nil {
+		return e, fmt.Errorf("golnk.IconEnvironment: %w", err)
+	}
+	return e, nil
+}
+
+// Mismatch returns true if TargetAnsi and TargetUnicode are both set and are
+// different paths. Explorer shows one of them while the other can be run.
+func (e EnvironmentVariableDataBlock) Mismatch() bool {
+	return pathsDiffer(e.TargetAnsi, e.TargetUnicode, e.codePage)
+}
+
+// Mismatch returns true if TargetAnsi and TargetUnicode are both set and are
+// different paths.
+func (e IconEnvironmentDataBlock) Mismatch() bool {
+	return pathsDiffer(e.TargetAnsi, e.TargetUnicode, e.codePage)
+}
+
+// pathsDiffer returns true if both paths are set and differ. Windows replaces
+// the characters that are not in the code page cp with '?', so a '?' or
+// U+FFFD in the ANSI path only matches those characters.
+func pathsDiffer(ansi, unicode string, cp int) bool {
+	if ansi == "" || unicode == "" {
+		return false
+	}
+	a, u := []rune(ansi), []rune(unicode)
+	if len(a) != len(u) {
+		return true
+	}
+	for i := range a {
+		if a[i] == u[i] {
+			continue
+		}
+		if (a[i] == '?' || a[i] == utf8.RuneError) && !inCodePage(u[i], cp) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// environmentTable prints the paths of an environment block in a table.
+func environmentTable(name, ansi, unicode string, mismatch bool) string {
+	rows := [][]string{
+		{"TargetAnsi", ansi},
+		{"TargetUnicode", unicode},
+	}
+	if mismatch {
+		rows = append(rows, []string{"Mismatch", "TargetAnsi and TargetUnicode are different"})
+	}
+
+	return blockTable(name, rows)
+}
+
+// String prints the EnvironmentVariableDataBlock in a table.
+func (e EnvironmentVariableDataBlock) String() string {
+	return environmentTable("EnvironmentVariableDataBlock", e.TargetAnsi, e.TargetUnicode, e.Mismatch())
+}
+
+// String prints the IconEnvironmentDataBlock in a table.
+func (e IconEnvironmentDataBlock) String() string {
+	return environmentTable("IconEnvironmentDataBlock", e.TargetAnsi, e.TargetUnicode, e.Mismatch())
+}
+
+// setCodePage decodes the ANSI paths of the blocks again with the code page.
+func (e *ExtraDataSection) setCodePage(cp int) {
+	for i, b := range e.Blocks {
+		switch p := b.Parsed.(type) {
+		case EnvironmentVariableDataBlock:
+			p.TargetAnsi, p.codePage = decodeANSI(p.TargetAnsiRaw, cp), cp
+			e.Blocks[i].Parsed = p
+		case IconEnvironmentDataBlock:
+			p.TargetAnsi, p.codePage = decodeANSI(p.TargetAnsiRaw, cp), cp
+			e.Blocks[i].Parsed = p
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// SpecialFolderDataBlock (section 2.5.9) contains the special folder (CSIDL)
+// that the target is in. Signature 0xA0000005.
+type SpecialFolderDataBlock struct {
+	// SpecialFolderID is the CSIDL of the folder, e.g. 0x05 for My Documents.
+	SpecialFolderID uint32
+
+	// Offset of the first ItemID in the LinkTargetIDList that is inside the
+	// folder, from the start of the IDList.
+	Offset uint32
+
+	// CSIDL is the constant of SpecialFolderID, e.g. CSIDL_PERSONAL. Empty
+	// if the value is not known.
+	CSIDL string
+
+	// Name of the folder, e.g. My Documents. SpecialFolderID in hex if the
+	// value is not known.
+	Name string
+
+	// ItemIndex is the index of the ItemID at Offset in
+	// LinkTargetIDList.IDList. -1 if no item starts at Offset or the block
+	// was not parsed by Read.
+	ItemIndex int
+}
+
+// KnownFolderDataBlock (section 2.5.6) contains the known folder that the
+// target is in. Signature 0xA000000B.
+type KnownFolderDataBlock struct {
+	// KnownFolderID is the KNOWNFOLDERID of the folder, e.g.
+	// {FDD39AD0-238F-46AF-ADB4-6C85480369C7} for Documents.
+	KnownFolderID GUID
+
+	// Offset of the first ItemID in the LinkTargetIDList that is inside the
+	// folder, from the start of the IDList.
+	Offset uint32
+
+	// FolderID is the constant of KnownFolderID, e.g. FOLDERID_Documents.
+	// Empty if the folder is not known.
+	FolderID string
+
+	// Name of the folder, e.g. Documents. KnownFolderID in registry format
+	// if the folder is not known.
+	Name string
+
+	// ItemIndex is the index of the ItemID at Offset in
+	// LinkTargetIDList.IDList. -1 if no item starts at Offset or the block
+	// was not parsed by Read.
+	ItemIndex int
+}
+
+// Sizes of the folder blocks after the signature.
+const (
+	specialFolderDataSize = 0x08
+	knownFolderDataSize   = 0x14
+)
+
+// SpecialFolder parses the data of a SpecialFolderDataBlock. data is
+// ExtraDataBlock.Data (everything after the signature).
+func SpecialFolder(data []byte) (s SpecialFolderDataBlock, err error) {
+	if len(data) < specialFolderDataSize {
+		return s, fmt.Errorf("golnk.SpecialFolder: invalid size - got %d bytes, want %d", len(data), specialFolderDataSize)
+	}
+	s.SpecialFolderID = binary.LittleEndian.Uint32(data[0:4])
+	s.Offset = binary.LittleEndian.Uint32(data[4:8])
+	s.CSIDL = specialFolders[s.SpecialFolderID].id
+	s.Name = specialFolderName(s.SpecialFolderID)
+	s.ItemIndex = -1
+	return s, nil
+}
+
+// KnownFolder parses the data of a KnownFolderDataBlock. data is
+// ExtraDataBlock.Data (everything after the signature).
+func KnownFolder(data []byte) (k KnownFolderDataBlock, err error) {
+	if len(data) < knownFolderDataSize {
+		return k, fmt.Errorf("golnk.KnownFolder: invalid size - got %d bytes, want %d", len(data), knownFolderDataSize)
+	}
+	copy(k.KnownFolderID[:], data[0:16])
+	k.Offset = binary.LittleEndian.Uint32(data[16:20])
+	k.FolderID = knownFolders[k.KnownFolderID.String()].id
+	k.Name = folderName(k.KnownFolderID)
+	k.ItemIndex = -1
+	return k, nil
+}
+
+// resolveFolders sets ItemIndex of the folder blocks from the IDList.
+func (f *LnkFile) resolveFolders() {
+	for i, b := range f.DataBlocks.Blocks {
+		switch p := b.Parsed.(type) {
+		case SpecialFolderDataBlock:
+			p.ItemIndex = f.IDList.ItemAt(p.Offset)
+			f.DataBlocks.Blocks[i].Parsed = p
+		case KnownFolderDataBlock:
+			p.ItemIndex = f.IDList.ItemAt(p.Offset)
+			f.DataBlocks.Blocks[i].Parsed = p
+		}
+	}
+}
+
+// folderTable prints a folder block in a table.
+func folderTable(name string, rows [][]string, offset uint32, index int) string {
+	rows = append(rows, []string{"Offset", uint32TableStr(offset)})
+	if index >= 0 {
+		rows = append(rows, []string{"ItemIndex", fmt.Sprint(index)})
+	}
+	return blockTable(name, rows)
+}
+
+// String prints the SpecialFolderDataBlock in a table.
+func (s SpecialFolderDataBlock) String() string {
+	return folderTable("SpecialFolderDataBlock", [][]string{
+		{"SpecialFolderID", uint32TableStr(s.SpecialFolderID)},
+		{"CSIDL", s.CSIDL},
+		{"Name", s.Name},
+	}, s.Offset, s.ItemIndex)
+}
+
+// String prints the KnownFolderDataBlock in a table.
+func (k KnownFolderDataBlock) String() string {
+	return folderTable("KnownFolderDataBlock", [][]string{
+		{"KnownFolderID", k.KnownFolderID.String()},
+		{"FolderID", k.FolderID},
+		{"Name", k.Name},
+	}, k.Offset, k.ItemIndex)
+}
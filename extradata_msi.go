@@ -0,0 +1,147 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// DarwinDataBlock (section 2.5.3) contains the Windows Installer (MSI)
+// descriptor of an advertised shortcut. The shortcut starts the product
+// through the installer, which repairs it if needed. Signature 0xA0000006.
+type DarwinDataBlock struct {
+	// DarwinDataAnsi is the descriptor in ANSI. 260 bytes on disk,
+	// null-terminated.
+	DarwinDataAnsi string
+	// DarwinDataUnicode is the descriptor in UTF-16. 520 bytes on disk,
+	// null-terminated. Optional.
+	DarwinDataUnicode string
+
+	// Descriptor is decoded from DarwinDataUnicode or DarwinDataAnsi if the
+	// Unicode version is empty. Zero if it cannot be decoded.
+	Descriptor MSIDescriptor
+	// DescriptorError is why the descriptor could not be decoded, empty if it
+	// was decoded.
+	DescriptorError string
+}
+
+// MSIDescriptor identifies the Windows Installer product, feature and
+// component of an advertised shortcut.
+type MSIDescriptor struct {
+	// ProductCode of the MSI package, e.g. {91120000-0030-0000-0000-0000000FF1CE}.
+	ProductCode GUID
+	// Feature is the name of the feature in the package, e.g. EXCELFiles.
+	Feature string
+	// ComponentCode of the component in the feature. Zero if the descriptor
+	// has no component because the feature has only one.
+	ComponentCode GUID
+}
+
+// darwinDataSize is the size of DarwinDataBlock after the signature.
+const darwinDataSize = 0x30C
+
+// Darwin parses the data of a DarwinDataBlock. data is ExtraDataBlock.Data
+// (everything after the signature).
+func Darwin(data []byte) (d DarwinDataBlock, err error) {
+	return darwin(data, newBudget(DefaultLimits))
+}
+
+// darwin parses a DarwinDataBlock and adds invalid strings to the warnings in
+// b.
+func darwin(data []byte, b *budget) (d DarwinDataBlock, err error) {
+	if len(data) < darwinDataSize {
+		return d, fmt.Errorf("golnk.Darwin: invalid size - got %d bytes, want %d", len(data), darwinDataSize)
+	}
+	d.DarwinDataAnsi = readString(data[:260])
+	d.DarwinDataUnicode, err = b.utf16("ExtraData", "DarwinDataBlock.DarwinDataUnicode", 0x104, data[260:780])
+	if err != nil {
+		return d, fmt.Errorf("golnk.Darwin: %w", err)
+	}
+
+	desc := d.DarwinDataUnicode
+	if desc == "" {
+		desc = d.DarwinDataAnsi
+	}
+	// The block is still useful without the descriptor.
+	if d.Descriptor, err = ParseMSIDescriptor(desc); err != nil {
+		d.Descriptor, d.DescriptorError = MSIDescriptor{}, err.Error()
+	}
+	return d, nil
+}
+
+// base85Alphabet is the alphabet of the packed GUIDs in MSI descriptors. The
+// index of a character is its value. It skips the characters that are not
+// valid in a descriptor or a registry value, e.g. "<", ">" and "|".
+const base85Alphabet = "!$%&'()*+,-.0123456789=?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[]^_`abcdefghijklmnopqrstuvwxyz{}~"
+
+// packedGUIDSize is the number of characters in a packed GUID.
+const packedGUIDSize = 20
+
+// maxFeatureSize is the maximum length of a feature name in a descriptor.
+const maxFeatureSize = 38
+
+// decodePackedGUID decodes a GUID packed in 20 base85 characters. Each group
+// of 5 characters is a little-endian uint32 of the GUID as it appears on disk,
+// least significant digit first.
+func decodePackedGUID(s string) (g GUID, err error) {
+	if len(s) < packedGUIDSize {
+		return g, fmt.Errorf("golnk.decodePackedGUID: invalid size - got %d characters, want %d", len(s), packedGUIDSize)
+	}
+	for i := 0; i < 4; i++ {
+		var val, base uint64 = 0, 1
+		for _, c := range []byte(s[i*5 : i*5+5]) {
+			digit := strings.IndexByte(base85Alphabet, c)
+			if digit < 0 {
+				return g, fmt.Errorf("golnk.decodePackedGUID: invalid character %q", c)
+			}
+			val += uint64(digit) * base
+			base *= 85
+		}
+		binary.LittleEndian.PutUint32(g[i*4:], uint32(val))
+	}
+	return g, nil
+}
+
+// ParseMSIDescriptor decodes a packed MSI descriptor, e.g. the one in a
+// DarwinDataBlock. The descriptor is the packed ProductCode, the Feature and
+// either ">" and the packed ComponentCode or "<" if there is no component.
+func ParseMSIDescriptor(s string) (d MSIDescriptor, err error) {
+	if d.ProductCode, err = decodePackedGUID(s); err != nil {
+		return d, fmt.Errorf("golnk.ParseMSIDescriptor: ProductCode - %w", err)
+	}
+	rest := s[packedGUIDSize:]
+	end := strings.IndexAny(rest, "><")
+	if end < 0 {
+		return d, fmt.Errorf("golnk.ParseMSIDescriptor: no feature terminator in %q", s)
+	}
+	if end > maxFeatureSize {
+		return d, fmt.Errorf("golnk.ParseMSIDescriptor: feature is too long - got %d characters, want at most %d", end, maxFeatureSize)
+	}
+	d.Feature = rest[:end]
+	if rest[end] == '>' {
+		if d.ComponentCode, err = decodePackedGUID(rest[end+1:]); err != nil {
+			return d, fmt.Errorf("golnk.ParseMSIDescriptor: ComponentCode - %w", err)
+		}
+	}
+	return d, nil
+}
+
+// String prints the DarwinDataBlock in a table.
+func (d DarwinDataBlock) String() string {
+	rows := [][]string{
+		{"DarwinDataAnsi", d.DarwinDataAnsi},
+		{"DarwinDataUnicode", d.DarwinDataUnicode},
+	}
+	if d.DescriptorError != "" {
+		rows = append(rows, []string{"DescriptorError", d.DescriptorError})
+	}
+	if d.Descriptor.ProductCode != (GUID{}) {
+		rows = append(rows, []string{"ProductCode", d.Descriptor.ProductCode.String()})
+		rows = append(rows, []string{"Feature", d.Descriptor.Feature})
+		if d.Descriptor.ComponentCode != (GUID{}) {
+			rows = append(rows, []string{"ComponentCode", d.Descriptor.ComponentCode.String()})
+		}
+	}
+
+	return blockTable("DarwinDataBlock", rows)
+}
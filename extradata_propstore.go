@@ -0,0 +1,507 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// PropertyStoreDataBlock (section 2.5.7) contains a serialized property
+// storage ([MS-PROPSTORE] section 2.2). Signature 0xA0000009.
+type PropertyStoreDataBlock struct {
+	// Stores are the property sets, one per FormatID.
+	Stores []PropertyStore
+}
+
+// PropertyStore is one Serialized Property Store. All properties in a store
+// share the same FormatID.
+type PropertyStore struct {
+	// Size of the store including this field.
+	Size uint32
+
+	// Must be 0x53505331 ("1SPS").
+	Version uint32
+
+	// FormatID identifies the property set. If it's
+	// D5CDD505-2E9C-101B-9397-08002B2CF9AE, properties have string names,
+	// otherwise they have integer IDs.
+	FormatID GUID
+
+	Properties []Property
+}
+
+// Property is one property value in a PropertyStore.
+type Property struct {
+	// ID of the property if the store uses integer names.
+	ID uint32
+
+	// Name of the property if the store uses string names.
+	Name string
+
+	// Canonical name of the property from FormatID and ID, e.g.
+	// System.AppUserModel.ID. Empty if the property is not known.
+	Key string
+
+	// Type is the VARTYPE of the value, e.g. 0x001F.
+	Type uint16
+
+	// TypeStr is the name of Type, e.g. VT_LPWSTR.
+	TypeStr string
+
+	// Value is the decoded value. The Go type depends on Type:
+	// VT_LPWSTR, VT_LPSTR and VT_BSTR are string, VT_UI4 is uint32, VT_UI8 is
+	// uint64, VT_FILETIME is time.Time, VT_CLSID is GUID, VT_BOOL is bool,
+	// VT_BLOB is []byte and vectors are []interface{}. Types without a decoder
+	// are the raw []byte of the value.
+	Value interface{}
+}
+
+const (
+	// propertyStoreVersion is "1SPS".
+	propertyStoreVersion = 0x53505331
+
+	// vtVector is combined with a type to create a vector of that type.
+	vtVector = 0x1000
+)
+
+// stringNameFormatID is the FormatID of stores with string names.
+var stringNameFormatID = GUID{0x05, 0xD5, 0xCD, 0xD5, 0x9C, 0x2E, 0x1B, 0x10, 0x93, 0x97, 0x08, 0x00, 0x2B, 0x2C, 0xF9, 0xAE}
+
+// vtTypes maps VARTYPE values to their names.
+var vtTypes = map[uint16]string{
+	0x0000: "VT_EMPTY",
+	0x0001: "VT_NULL",
+	0x0002: "VT_I2",
+	0x0003: "VT_I4",
+	0x0004: "VT_R4",
+	0x0005: "VT_R8",
+	0x0006: "VT_CY",
+	0x0007: "VT_DATE",
+	0x0008: "VT_BSTR",
+	0x000A: "VT_ERROR",
+	0x000B: "VT_BOOL",
+	0x000E: "VT_DECIMAL",
+	0x0010: "VT_I1",
+	0x0011: "VT_UI1",
+	0x0012: "VT_UI2",
+	0x0013: "VT_UI4",
+	0x0014: "VT_I8",
+	0x0015: "VT_UI8",
+	0x0016: "VT_INT",
+	0x0017: "VT_UINT",
+	0x001E: "VT_LPSTR",
+	0x001F: "VT_LPWSTR",
+	0x0040: "VT_FILETIME",
+	0x0041: "VT_BLOB",
+	0x0042: "VT_STREAM",
+	0x0043: "VT_STORAGE",
+	0x0044: "VT_STREAMED_OBJECT",
+	0x0045: "VT_STORED_OBJECT",
+	0x0046: "VT_BLOB_OBJECT",
+	0x0047: "VT_CF",
+	0x0048: "VT_CLSID",
+	0x0049: "VT_VERSIONED_STREAM",
+}
+
+// vtType returns the name of a VARTYPE. Vectors are prefixed with
+// "VT_VECTOR|".
+func vtType(vt uint16) string {
+	prefix := ""
+	if vt&vtVector != 0 {
+		prefix = "VT_VECTOR|"
+	}
+	if val, exists := vtTypes[vt&^vtVector]; exists {
+		return prefix + val
+	}
+	return prefix + "VT_UNKNOWN - " + uint32StrHex(uint32(vt&^vtVector))
+}
+
+// propertyKey is a FormatID and property ID pair.
+type propertyKey struct {
+	formatID string
+	id       uint32
+}
+
+// propertyKeys maps well-known property keys to their canonical names.
+var propertyKeys = map[propertyKey]string{
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 4}:   "System.ItemTypeText",
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 10}:  "System.ItemNameDisplay",
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 12}:  "System.Size",
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 13}:  "System.FileAttributes",
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 14}:  "System.DateModified",
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 15}:  "System.DateCreated",
+	{"{B725F130-47EF-101A-A5F1-02608C9EEBAC}", 16}:  "System.DateAccessed",
+	{"{F29F85E0-4FF9-1068-AB91-08002B27B3D9}", 2}:   "System.Title",
+	{"{F29F85E0-4FF9-1068-AB91-08002B27B3D9}", 3}:   "System.Subject",
+	{"{F29F85E0-4FF9-1068-AB91-08002B27B3D9}", 4}:   "System.Author",
+	{"{F29F85E0-4FF9-1068-AB91-08002B27B3D9}", 5}:   "System.Keywords",
+	{"{F29F85E0-4FF9-1068-AB91-08002B27B3D9}", 6}:   "System.Comment",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 2}:   "System.AppUserModel.RelaunchCommand",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 3}:   "System.AppUserModel.RelaunchIconResource",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 4}:   "System.AppUserModel.RelaunchDisplayNameResource",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 5}:   "System.AppUserModel.ID",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 6}:   "System.AppUserModel.IsDestListSeparator",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 9}:   "System.AppUserModel.PreventPinning",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 15}:  "System.AppUserModel.PackageInstallPath",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 17}:  "System.AppUserModel.PackageFamilyName",
+	{"{9F4C2855-9F79-4B39-A8D0-E1D42DE1D5F3}", 21}:  "System.AppUserModel.PackageFullName",
+	{"{B9B4B3FC-2B51-4A42-B5D8-324146AFCF25}", 2}:   "System.Link.TargetParsingPath",
+	{"{28636AA6-953D-11D2-B5D6-00C04FD918D0}", 30}:  "System.ParsingPath",
+	{"{446D16B1-8DAD-4870-A748-402EA43D788C}", 100}: "System.ThumbnailCacheId",
+	{"{446D16B1-8DAD-4870-A748-402EA43D788C}", 104}: "System.VolumeId",
+}
+
+// PropertyStoreBlock parses the data of a PropertyStoreDataBlock. data is
+// ExtraDataBlock.Data (everything after the signature).
+func PropertyStoreBlock(data []byte) (p PropertyStoreDataBlock, err error) {
+	return propertyStoreBlock(data, newBudget(DefaultLimits))
+}
+
+// propertyStoreBlock parses a PropertyStoreDataBlock and adds invalid strings
+// to the warnings in b.
+func propertyStoreBlock(data []byte, b *budget) (p PropertyStoreDataBlock, err error) {
+	r := bytes.NewReader(data)
+	for {
+		// Offset of the store.
+		offset := r.Size() - int64(r.Len())
+		var size uint32
+		err = binary.Read(r, binary.LittleEndian, &size)
+		if err != nil {
+			return p, fmt.Errorf("golnk.PropertyStoreBlock: read store size - %s", err.Error())
+		}
+		// A zero size is the terminator.
+		if size == 0 {
+			break
+		}
+		if size < 24 || uint64(size-4) > uint64(r.Len()) {
+			return p, fmt.Errorf("golnk.PropertyStoreBlock: invalid store size %d", size)
+		}
+		storeData := make([]byte, size-4)
+		r.Read(storeData)
+
+		n := len(b.warnings)
+		store, err := propertyStore(size, storeData, b)
+		b.shiftWarnings(n, offset+4)
+		if err != nil {
+			return p, fmt.Errorf("golnk.PropertyStoreBlock: %s", err.Error())
+		}
+		p.Stores = append(p.Stores, store)
+	}
+	return p, nil
+}
+
+// propertyStore parses one Serialized Property Store. data starts after the
+// size field.
+func propertyStore(size uint32, data []byte, b *budget) (s PropertyStore, err error) {
+	s.Size = size
+	r := bytes.NewReader(data)
+	binary.Read(r, binary.LittleEndian, &s.Version)
+	if s.Version != propertyStoreVersion {
+		return s, fmt.Errorf("invalid store version - got %s, want %s",
+			uint32StrHex(s.Version), uint32StrHex(propertyStoreVersion))
+	}
+	binary.Read(r, binary.LittleEndian, &s.FormatID)
+	stringNames := s.FormatID == stringNameFormatID
+
+	for {
+		// Offset of the value.
+		offset := r.Size() - int64(r.Len())
+		var valueSize uint32
+		err = binary.Read(r, binary.LittleEndian, &valueSize)
+		if err != nil {
+			return s, fmt.Errorf("read value size - %s", err.Error())
+		}
+		// A zero size is the terminator.
+		if valueSize == 0 {
+			break
+		}
+		if valueSize < 4 || uint64(valueSize-4) > uint64(r.Len()) {
+			return s, fmt.Errorf("invalid value size %d", valueSize)
+		}
+		valueData := make([]byte, valueSize-4)
+		r.Read(valueData)
+
+		n := len(b.warnings)
+		prop, err := property(valueData, stringNames, b)
+		b.shiftWarnings(n, offset+4)
+		if err != nil {
+			return s, err
+		}
+		if !stringNames {
+			prop.Key = propertyKeys[propertyKey{s.FormatID.String(), prop.ID}]
+		}
+		s.Properties = append(s.Properties, prop)
+	}
+	return s, nil
+}
+
+// property parses one Serialized Property Value. data starts after the value
+// size field.
+func property(data []byte, stringName bool, b *budget) (p Property, err error) {
+	r := bytes.NewReader(data)
+	if stringName {
+		var nameSize uint32
+		err = binary.Read(r, binary.LittleEndian, &nameSize)
+		if err != nil {
+			return p, fmt.Errorf("read name size - %s", err.Error())
+		}
+		// Skip the reserved byte.
+		r.ReadByte()
+		if uint64(nameSize) > uint64(r.Len()) {
+			return p, fmt.Errorf("invalid name size %d", nameSize)
+		}
+		name := make([]byte, nameSize)
+		r.Read(name)
+		// The name is after the size and the reserved byte.
+		p.Name, err = b.utf16("ExtraData", "PropertyStoreDataBlock.Name", 5, name)
+		if err != nil {
+			return p, fmt.Errorf("read name - %w", err)
+		}
+	} else {
+		err = binary.Read(r, binary.LittleEndian, &p.ID)
+		if err != nil {
+			return p, fmt.Errorf("read property ID - %s", err.Error())
+		}
+		// Skip the reserved byte.
+		r.ReadByte()
+	}
+
+	// TypedPropertyValue: uint16 type, uint16 padding and the value.
+	var padding uint16
+	err = binary.Read(r, binary.LittleEndian, &p.Type)
+	if err != nil {
+		return p, fmt.Errorf("read property type - %s", err.Error())
+	}
+	binary.Read(r, binary.LittleEndian, &padding)
+	p.TypeStr = vtType(p.Type)
+
+	p.Value, err = typedValue(r, p.Type, b)
+	if err != nil {
+		return p, fmt.Errorf("read %s value - %s", p.TypeStr, err.Error())
+	}
+	return p, nil
+}
+
+// typedValue reads the value of a TypedPropertyValue. Vectors are read as
+// a uint32 count followed by the elements.
+func typedValue(r *bytes.Reader, vt uint16, b *budget) (interface{}, error) {
+	if vt&vtVector == 0 {
+		return scalarValue(r, vt, b)
+	}
+	var count uint32
+	if err := binary.Read(r, binary.LittleEndian, &count); err != nil {
+		return nil, err
+	}
+	// Every element is at least one byte.
+	if uint64(count) > uint64(r.Len()) {
+		return nil, fmt.Errorf("invalid vector count %d", count)
+	}
+	values := make([]interface{}, 0, count)
+	for i := uint32(0); i < count; i++ {
+		v, err := scalarValue(r, vt&^vtVector, b)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// scalarValue reads one value of type vt. Strings are stored in UTF-16 in
+// property stores, even VT_LPSTR and VT_BSTR. Invalid strings are added to
+// the warnings in b with the offset in the reader.
+func scalarValue(r *bytes.Reader, vt uint16, b *budget) (interface{}, error) {
+	switch vt {
+	case 0x0000, 0x0001: // VT_EMPTY, VT_NULL
+		return nil, nil
+	case 0x0002: // VT_I2
+		var v int16
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0003, 0x0016: // VT_I4, VT_INT
+		var v int32
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0004: // VT_R4
+		var v float32
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0005: // VT_R8
+		var v float64
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0006, 0x0014: // VT_CY, VT_I8
+		var v int64
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0007: // VT_DATE
+		var v float64
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return oleDate(v), err
+	case 0x000A, 0x0013, 0x0017: // VT_ERROR, VT_UI4, VT_UINT
+		var v uint32
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x000B: // VT_BOOL
+		var v uint16
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v != 0, err
+	case 0x0010: // VT_I1
+		var v int8
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0011: // VT_UI1
+		var v uint8
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0012: // VT_UI2
+		var v uint16
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0015: // VT_UI8
+		var v uint64
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x0040: // VT_FILETIME
+		var v [8]byte
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return toTime(v), err
+	case 0x0048: // VT_CLSID
+		var v GUID
+		err := binary.Read(r, binary.LittleEndian, &v)
+		return v, err
+	case 0x001F: // VT_LPWSTR: uint32 number of characters, including the null.
+		var length uint32
+		if err := binary.Read(r, binary.LittleEndian, &length); err != nil {
+			return nil, err
+		}
+		offset := r.Size() - int64(r.Len())
+		str, err := readPadded(r, uint64(length)*2)
+		if err != nil {
+			return nil, err
+		}
+		return b.utf16("ExtraData", "PropertyStoreDataBlock.Value", offset, str)
+	case 0x0008, 0x001E, 0x0041: // VT_BSTR, VT_LPSTR, VT_BLOB: uint32 size in bytes.
+		var size uint32
+		if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+			return nil, err
+		}
+		offset := r.Size() - int64(r.Len())
+		str, err := readPadded(r, uint64(size))
+		if err != nil || vt == 0x0041 {
+			return str, err
+		}
+		return b.utf16("ExtraData", "PropertyStoreDataBlock.Value", offset, str)
+	}
+	// No decoder, return the rest of the value.
+	rest := make([]byte, r.Len())
+	r.Read(rest)
+	return rest, nil
+}
+
+// readPadded reads n bytes from the reader and skips the padding to the next
+// multiple of four.
+func readPadded(r *bytes.Reader, n uint64) ([]byte, error) {
+	if n > uint64(r.Len()) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	if pad := (4 - n%4) % 4; pad <= uint64(r.Len()) {
+		r.Seek(int64(pad), io.SeekCurrent)
+	}
+	return b, nil
+}
+
+// oleDate converts an OLE Automation date (days since 1899-12-30) to
+// time.Time.
+func oleDate(d float64) time.Time {
+	if math.IsNaN(d) || math.IsInf(d, 0) {
+		return time.Time{}
+	}
+	days, frac := math.Modf(d)
+	t := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(days))
+	return t.Add(time.Duration(math.Abs(frac) * float64(24*time.Hour)))
+}
+
+// Get returns the value of the property with the FormatID and ID. ok is false
+// if the property is not in the block.
+func (p PropertyStoreDataBlock) Get(formatID GUID, id uint32) (value interface{}, ok bool) {
+	for _, s := range p.Stores {
+		if s.FormatID != formatID {
+			continue
+		}
+		for _, prop := range s.Properties {
+			if prop.Name == "" && prop.ID == id {
+				return prop.Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// GetKey returns the value of a property by its canonical name, e.g.
+// System.AppUserModel.ID. ok is false if the property is not in the block.
+func (p PropertyStoreDataBlock) GetKey(key string) (value interface{}, ok bool) {
+	for _, s := range p.Stores {
+		for _, prop := range s.Properties {
+			if prop.Key == key {
+				return prop.Value, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// String prints the PropertyStoreDataBlock in a table.
+func (p PropertyStoreDataBlock) String() string {
+	var sb strings.Builder
+
+	table := tablewriter.NewWriter(&sb)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"PropertyStoreDataBlock", "Type", "Value"})
+
+	for _, s := range p.Stores {
+		for _, prop := range s.Properties {
+			var name string
+			switch {
+			case prop.Name != "":
+				name = prop.Name
+			case prop.Key != "":
+				name = prop.Key
+			default:
+				name = fmt.Sprintf("%s %d", s.FormatID, prop.ID)
+			}
+			table.Append([]string{name, prop.TypeStr, propertyValueStr(prop.Value)})
+		}
+	}
+
+	table.Render()
+	return sb.String()
+}
+
+// propertyValueStr converts a property value to string for printing.
+func propertyValueStr(v interface{}) string {
+	switch val := v.(type) {
+	case []byte:
+		return hex.EncodeToString(val)
+	case []interface{}:
+		strs := make([]string, len(val))
+		for i, e := range val {
+			strs[i] = propertyValueStr(e)
+		}
+		return strings.Join(strs, "\n")
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
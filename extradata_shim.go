@@ -0,0 +1,144 @@
+package lnk
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ShimDataBlock (section 2.5.8) contains the name of the application
+// compatibility layer applied to the target. It's used if RunWithShimLayer is
+// set. Signature 0xA0000008.
+type ShimDataBlock struct {
+	// LayerName is the layer, e.g. WinXPSp3. UTF-16 on disk, at least 128
+	// bytes. Layers can be separated by spaces like in the AppCompatFlags
+	// registry key, e.g. "RunAsAdmin WinXPSp3".
+	LayerName string
+
+	// Layers are the layers in LayerName.
+	Layers []ShimLayer
+}
+
+// ShimLayer is one compatibility layer.
+type ShimLayer struct {
+	// Name of the layer as it appears in LayerName.
+	Name string
+	// Description of the layer, empty if the layer is not known.
+	Description string
+	// Privilege is true if the layer changes the privileges of the target,
+	// e.g. RunAsAdmin or RunAsInvoker.
+	Privilege bool
+}
+
+// shimDataMinSize is the minimum size of ShimDataBlock after the signature.
+const shimDataMinSize = 0x80
+
+// shimLayerInfo describes a known compatibility layer.
+type shimLayerInfo struct {
+	description string
+	privilege   bool
+}
+
+// shimLayers are the known compatibility layers. The keys are upper case
+// because layer names are not case sensitive.
+var shimLayers = map[string]shimLayerInfo{
+	// Privileges.
+	"RUNASINVOKER":         {"Run with the privileges of the parent process, without a UAC prompt", true},
+	"RUNASADMIN":           {"Run as administrator, with a UAC prompt", true},
+	"RUNASHIGHEST":         {"Run with the highest privileges available to the user", true},
+	"ELEVATECREATEPROCESS": {"Elevate child processes that require administrator privileges", true},
+	"FORCEADMINACCESS":     {"Report that the user is an administrator", true},
+
+	// Windows versions.
+	"WIN95":       {"Windows 95", false},
+	"WIN98":       {"Windows 98 / Windows ME", false},
+	"NT4SP5":      {"Windows NT 4.0 Service Pack 5", false},
+	"WIN2000":     {"Windows 2000", false},
+	"WINXP":       {"Windows XP", false},
+	"WINXPSP2":    {"Windows XP Service Pack 2", false},
+	"WINXPSP3":    {"Windows XP Service Pack 3", false},
+	"WINSRV03":    {"Windows Server 2003", false},
+	"WINSRV03SP1": {"Windows Server 2003 Service Pack 1", false},
+	"WINSRV08SP1": {"Windows Server 2008 Service Pack 1", false},
+	"VISTARTM":    {"Windows Vista", false},
+	"VISTASP1":    {"Windows Vista Service Pack 1", false},
+	"VISTASP2":    {"Windows Vista Service Pack 2", false},
+	"WIN7RTM":     {"Windows 7", false},
+	"WIN8RTM":     {"Windows 8", false},
+	"WIN81RTM":    {"Windows 8.1", false},
+
+	// Display.
+	"256COLOR":                       {"Reduced colour mode (256 colours)", false},
+	"16BITCOLOR":                     {"Reduced colour mode (16-bit colour)", false},
+	"640X480":                        {"Run in 640 x 480 screen resolution", false},
+	"DISABLETHEMES":                  {"Disable visual themes", false},
+	"DISABLEDWM":                     {"Disable desktop composition", false},
+	"HIGHDPIAWARE":                   {"High DPI scaling done by the application", false},
+	"DPIUNAWARE":                     {"High DPI scaling done by the system", false},
+	"GDIDPISCALING":                  {"High DPI scaling done by the system (enhanced)", false},
+	"DISABLEDXMAXIMIZEDWINDOWEDMODE": {"Disable full screen optimizations", false},
+	"TRANSFORMLEGACYCOLORMANAGED":    {"Use the legacy display ICC colour management", false},
+}
+
+// Shim parses the data of a ShimDataBlock. data is ExtraDataBlock.Data
+// (everything after the signature).
+func Shim(data []byte) (s ShimDataBlock, err error) {
+	return shim(data, newBudget(DefaultLimits))
+}
+
+// shim parses a ShimDataBlock and adds an invalid LayerName to the warnings in
+// b.
+func shim(data []byte, b *budget) (s ShimDataBlock, err error) {
+	if len(data) < shimDataMinSize {
+		return s, fmt.Errorf("golnk.Shim: invalid size - got %d bytes, want at least %d", len(data), shimDataMinSize)
+	}
+	s.LayerName, err = b.utf16("ExtraData", "ShimDataBlock.LayerName", 0, data)
+	if err != nil {
+		return s, fmt.Errorf("golnk.Shim: %w", err)
+	}
+	s.Layers = shimLayerList(s.LayerName)
+	return s, nil
+}
+
+// shimLayerList returns the layers in a layer string. The "~" flag and other
+// non-layer markers of the registry format are skipped.
+func shimLayerList(name string) []ShimLayer {
+	var layers []ShimLayer
+	for _, n := range strings.Fields(name) {
+		if strings.Trim(n, "~#$") == "" {
+			continue
+		}
+		info := shimLayers[strings.ToUpper(n)]
+		layers = append(layers, ShimLayer{Name: n, Description: info.description, Privilege: info.privilege})
+	}
+	return layers
+}
+
+// Privileged returns true if one of the layers changes the privileges of the
+// target.
+func (s ShimDataBlock) Privileged() bool {
+	for _, l := range s.Layers {
+		if l.Privilege {
+			return true
+		}
+	}
+	return false
+}
+
+// String prints the ShimDataBlock in a table.
+func (s ShimDataBlock) String() string {
+	rows := [][]string{
+		{"LayerName", s.LayerName},
+	}
+	for _, l := range s.Layers {
+		desc := l.Description
+		if desc == "" {
+			desc = "Unknown layer"
+		}
+		if l.Privilege {
+			desc += " (privilege)"
+		}
+		rows = append(rows, []string{l.Name, desc})
+	}
+
+	return blockTable("ShimDataBlock", rows)
+}
@@ -0,0 +1,509 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// block returns the parsed block with the signature from the sample file.
+func block(t *testing.T, filename string, sig uint32) fmt.Stringer {
+	t.Helper()
+	f, err := File(filename)
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	for _, b := range f.DataBlocks.Blocks {
+		if b.Signature == sig {
+			return b.Parsed
+		}
+	}
+	t.Fatalf("%s has no block with signature %x", filename, sig)
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     TrackerDataBlock
+	}{
+		{"nem", "test/nem.test", TrackerDataBlock{
+			MachineID:     "sagerez",
+			DroidVolumeID: mustGUID(t, "{38648BD4-606F-459B-91E5-D8883D4B70B1}"),
+			DroidFileID:   mustGUID(t, "{BA9EB0FB-C5C5-11E5-B2AD-A434D943F363}"),
+			FileID: UUIDv1{
+				Time:          time.Date(2016, 1, 28, 13, 47, 53, 485951500, time.UTC),
+				ClockSequence: 12973,
+				Node:          "a4:34:d9:43:f3:63",
+			},
+		}},
+		{"vscode", "test/Visual Studio Code.lnk", TrackerDataBlock{
+			MachineID:     "hakimian-5520",
+			DroidVolumeID: mustGUID(t, "{6D5D77AE-97CB-43FD-BF9A-87CB9E27BE00}"),
+			DroidFileID:   mustGUID(t, "{9917B40A-D928-11E8-9896-005056C00008}"),
+			FileID: UUIDv1{
+				Time:          time.Date(2018, 10, 26, 14, 8, 22, 518682600, time.UTC),
+				ClockSequence: 6294,
+				Node:          "00:50:56:c0:00:08",
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := block(t, tt.filename, 0xA0000003).(TrackerDataBlock)
+			if !ok {
+				t.Fatalf("block is not a TrackerDataBlock")
+			}
+			if got.Length != trackerDataSize {
+				t.Errorf("Length = %v, want %v", got.Length, trackerDataSize)
+			}
+			if got.MachineID != tt.want.MachineID {
+				t.Errorf("MachineID = %v, want %v", got.MachineID, tt.want.MachineID)
+			}
+			if got.DroidVolumeID != tt.want.DroidVolumeID {
+				t.Errorf("DroidVolumeID = %v, want %v", got.DroidVolumeID, tt.want.DroidVolumeID)
+			}
+			if got.DroidFileID != tt.want.DroidFileID {
+				t.Errorf("DroidFileID = %v, want %v", got.DroidFileID, tt.want.DroidFileID)
+			}
+			if !got.FileID.Time.Equal(tt.want.FileID.Time) ||
+				got.FileID.ClockSequence != tt.want.FileID.ClockSequence ||
+				got.FileID.Node != tt.want.FileID.Node {
+				t.Errorf("FileID = %+v, want %+v", got.FileID, tt.want.FileID)
+			}
+		})
+	}
+}
+
+// mustGUID parses a GUID string and fails the test if it's invalid.
+func mustGUID(t *testing.T, s string) GUID {
+	t.Helper()
+	g, err := parseGUID(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestPropertyStore(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		key      string
+		want     interface{}
+	}{
+		{"vscode-appid", "test/Visual Studio Code.lnk", "System.AppUserModel.ID", "Microsoft.VisualStudioCode"},
+		{"vscode-volumeid", "test/Visual Studio Code.lnk", "System.VolumeId", mustGUID(t, "{003454A6-3B52-4EDF-8B65-403963122DEB}")},
+		{"store-appid", "test/Windows Store.lnk", "System.AppUserModel.ID", "winstore_cw5n1h2txyewy!Windows.Store"},
+		{"store-tile", "test/Windows Store.lnk", "System.AppUserModel.PackageFamilyName", "winstore_cw5n1h2txyewy"},
+		{"vbox-parsingpath", "test/vbox-svr-win10.lnk", "System.ParsingPath", `\\VBOXSVR`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, ok := block(t, tt.filename, 0xA0000009).(PropertyStoreDataBlock)
+			if !ok {
+				t.Fatalf("block is not a PropertyStoreDataBlock")
+			}
+			got, ok := p.GetKey(tt.key)
+			if !ok {
+				t.Fatalf("GetKey(%s) not found", tt.key)
+			}
+			if got != tt.want {
+				t.Errorf("GetKey(%s) = %v, want %v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropertyStoreTypes(t *testing.T) {
+	fmtID := mustGUID(t, "{B725F130-47EF-101A-A5F1-02608C9EEBAC}")
+	ft := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	ftBytes := fromTime(ft)
+	tests := []struct {
+		name  string
+		vt    uint16
+		value []byte
+		want  interface{}
+	}{
+		{"VT_UI8", 0x0015, uint64Byte(1 << 40), uint64(1 << 40)},
+		{"VT_BOOL", 0x000B, []byte{0xFF, 0xFF, 0x00, 0x00}, true},
+		{"VT_FILETIME", 0x0040, ftBytes[:], ft.Local()},
+		{"VT_BLOB", 0x0041, []byte{0x02, 0x00, 0x00, 0x00, 0xAA, 0xBB, 0x00, 0x00}, []byte{0xAA, 0xBB}},
+		{"VT_VECTOR|VT_LPWSTR", 0x101F, []byte{
+			0x02, 0x00, 0x00, 0x00, // Count.
+			0x02, 0x00, 0x00, 0x00, 'a', 0x00, 0x00, 0x00, // "a".
+			0x03, 0x00, 0x00, 0x00, 'b', 0x00, 'c', 0x00, 0x00, 0x00, 0x00, 0x00, // "bc" and padding.
+		}, []interface{}{"a", "bc"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Serialized Property Value with ID 12.
+			var value []byte
+			value = append(value, uint32Byte(12)...)
+			value = append(value, 0x00)
+			value = append(value, uint16Byte(tt.vt)...)
+			value = append(value, 0x00, 0x00)
+			value = append(value, tt.value...)
+			value = append(uint32Byte(uint32(len(value)+4)), value...)
+
+			// Serialized Property Store.
+			var store []byte
+			store = append(store, uint32Byte(propertyStoreVersion)...)
+			store = append(store, fmtID[:]...)
+			store = append(store, value...)
+			store = append(store, uint32Byte(0)...)
+			store = append(uint32Byte(uint32(len(store)+4)), store...)
+			store = append(store, uint32Byte(0)...)
+
+			p, err := PropertyStoreBlock(store)
+			if err != nil {
+				t.Fatalf("PropertyStoreBlock() error = %v", err)
+			}
+			got, ok := p.Get(fmtID, 12)
+			if !ok {
+				t.Fatalf("Get() not found")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Get() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testBlock is the data of an ExtraData block after the signature. It builds
+// the blocks that are not in the samples in test/.
+type testBlock []byte
+
+// newTestBlock returns a zero block of size bytes.
+func newTestBlock(size int) testBlock {
+	return make(testBlock, size)
+}
+
+// put16 writes a uint16 at off.
+func (b testBlock) put16(off int, v uint16) testBlock {
+	copy(b[off:], uint16Byte(v))
+	return b
+}
+
+// put32 writes a uint32 at off.
+func (b testBlock) put32(off int, v uint32) testBlock {
+	copy(b[off:], uint32Byte(v))
+	return b
+}
+
+// ansi writes s at off.
+func (b testBlock) ansi(off int, s string) testBlock {
+	copy(b[off:], s)
+	return b
+}
+
+// unicode writes s in UTF-16LE at off.
+func (b testBlock) unicode(off int, s string) testBlock {
+	copy(b[off:], unicodeBytes(s))
+	return b
+}
+
+// block returns the block with its size and signature.
+func (b testBlock) block(sig uint32) []byte {
+	data := append(uint32Byte(uint32(len(b)+8)), uint32Byte(sig)...)
+	return append(data, b...)
+}
+
+// extraData returns an ExtraData section with the blocks and a TerminalBlock.
+func extraData(blocks ...[]byte) []byte {
+	var section []byte
+	for _, b := range blocks {
+		section = append(section, b...)
+	}
+	return append(section, 0, 0, 0, 0)
+}
+
+// parseBlocks parses an ExtraData section and returns the typed blocks.
+func parseBlocks(t *testing.T, section []byte) []fmt.Stringer {
+	t.Helper()
+	extra, err := DataBlock(bytes.NewReader(section))
+	if err != nil {
+		t.Fatalf("DataBlock() error = %v", err)
+	}
+	var blocks []fmt.Stringer
+	for _, b := range extra.Blocks {
+		blocks = append(blocks, b.Parsed)
+	}
+	return blocks
+}
+
+// consoleBlock returns an ExtraData section with a ConsoleDataBlock and a
+// ConsoleFEDataBlock as saved for cmd.exe.
+func consoleBlock() []byte {
+	console := newTestBlock(consoleDataSize).
+		put16(0x00, 0x07).
+		put16(0x02, 0xF5).
+		put16(0x04, 120).
+		put16(0x06, 9001).
+		put16(0x08, 120).
+		put16(0x0A, 30).
+		put16(0x0C, 0xFFF8). // -8
+		put32(0x18, 16<<16).
+		put32(0x1C, 0x36).
+		put32(0x20, 400).
+		unicode(0x24, "Consolas").
+		put32(0x64, 25).
+		put32(0x6C, 1).
+		put32(0x70, 1).
+		put32(0x78, 50).
+		put32(0x7C, 4).
+		put32(0x84, 0x000C0C0C).
+		put32(0x84+15*4, 0x00F2F2F2)
+	fe := newTestBlock(consoleFEDataSize).put32(0, 65001)
+	return extraData(console.block(0xA0000002), fe.block(0xA0000004))
+}
+
+func TestConsole(t *testing.T) {
+	blocks := parseBlocks(t, consoleBlock())
+	got, ok := blocks[0].(ConsoleDataBlock)
+	if !ok {
+		t.Fatalf("block is not a ConsoleDataBlock")
+	}
+	want := ConsoleDataBlock{
+		FillAttributes:         FlagMap{"FOREGROUND_BLUE": true, "FOREGROUND_GREEN": true, "FOREGROUND_RED": true},
+		PopupFillAttributes:    FlagMap{"FOREGROUND_BLUE": true, "FOREGROUND_RED": true, "BACKGROUND_BLUE": true, "BACKGROUND_GREEN": true, "BACKGROUND_RED": true, "BACKGROUND_INTENSITY": true},
+		ScreenBufferSizeX:      120,
+		ScreenBufferSizeY:      9001,
+		WindowSizeX:            120,
+		WindowSizeY:            30,
+		WindowOriginX:          -8,
+		FontSize:               16 << 16,
+		FontFamily:             "FF_MODERN | TMPF_VECTOR | TMPF_TRUETYPE",
+		FontWeight:             400,
+		FaceName:               "Consolas",
+		CursorSize:             25,
+		QuickEdit:              true,
+		InsertMode:             true,
+		HistoryBufferSize:      50,
+		NumberOfHistoryBuffers: 4,
+	}
+	want.ColorTable[0] = RGB{0x0C, 0x0C, 0x0C}
+	want.ColorTable[15] = RGB{0xF2, 0xF2, 0xF2}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Console() = %+v, want %+v", got, want)
+	}
+	if s := got.String(); !strings.Contains(s, "#0C0C0C") || !strings.Contains(s, "| FontHeight             | 16 ") {
+		t.Errorf("String() is missing the colour table or font size:\n%s", s)
+	}
+
+	fe, ok := blocks[1].(ConsoleFEDataBlock)
+	if !ok || fe.CodePage != 65001 {
+		t.Errorf("ConsoleFE() = %+v, want CodePage 65001", blocks[1])
+	}
+
+	if _, err := Console(make([]byte, consoleDataSize-1)); err == nil {
+		t.Errorf("Console() with a short block error = nil")
+	}
+}
+
+func TestMSIDescriptor(t *testing.T) {
+	// Descriptors of the Excel 2007 shortcuts, the component is EXCEL.EXE.
+	product := mustGUID(t, "{91120000-0030-0000-0000-0000000FF1CE}")
+	component := mustGUID(t, "{0638C49D-BB8B-4CD1-B191-052E8F325736}")
+	tests := []struct {
+		name    string
+		desc    string
+		want    MSIDescriptor
+		wantErr bool
+	}{
+		{"component", "w_1^VX!!!!!!!!!MKKSkEXCELFiles>tW{~$4Q]c@II=l2xaTO5", MSIDescriptor{product, "EXCELFiles", component}, false},
+		{"no-component", "w_1^VX!!!!!!!!!MKKSkEXCELFiles<", MSIDescriptor{product, "EXCELFiles", GUID{}}, false},
+		{"no-terminator", "w_1^VX!!!!!!!!!MKKSkEXCELFiles", MSIDescriptor{}, true},
+		{"bad-character", "w_1^VX!!!!!!!!!MKKS|EXCELFiles<", MSIDescriptor{}, true},
+		{"short-component", "w_1^VX!!!!!!!!!MKKSkEXCELFiles>tW{~$", MSIDescriptor{}, true},
+		{"short", "w_1^VX", MSIDescriptor{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMSIDescriptor(tt.desc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMSIDescriptor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseMSIDescriptor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// The block decodes the Unicode descriptor.
+	excel := "w_1^VX!!!!!!!!!MKKSkEXCELFiles>tW{~$4Q]c@II=l2xaTO5"
+	data := newTestBlock(darwinDataSize).
+		ansi(0, "w_1^VX!!!!!!!!!MKKSkWORDFiles<").
+		unicode(260, excel)
+	d, ok := parseBlocks(t, extraData(data.block(0xA0000006)))[0].(DarwinDataBlock)
+	if !ok {
+		t.Fatalf("block is not a DarwinDataBlock")
+	}
+	want := MSIDescriptor{product, "EXCELFiles", component}
+	if d.DarwinDataAnsi != "w_1^VX!!!!!!!!!MKKSkWORDFiles<" || d.Descriptor != want || d.DescriptorError != "" {
+		t.Errorf("Darwin() = %+v, want the Unicode descriptor", d)
+	}
+
+	// A descriptor that cannot be decoded is kept with the error.
+	data = newTestBlock(darwinDataSize).ansi(0, "w_1^VX").unicode(260, "w_1^VX")
+	if d, err := Darwin(data); err != nil || d.DarwinDataUnicode != "w_1^VX" || d.DescriptorError == "" {
+		t.Errorf("Darwin() = %+v, %v, want the strings and DescriptorError", d, err)
+	}
+}
+
+func TestEnvironment(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     string
+	}{
+		{"store", "test/Windows Store.lnk", `%windir%\WinStore\WinStore.htm`},
+		{"vbox", "test/vbox-svr-win10.lnk", `\\VBOXSVR`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := block(t, tt.filename, environmentSignature).(EnvironmentVariableDataBlock)
+			if !ok {
+				t.Fatalf("block is not an EnvironmentVariableDataBlock")
+			}
+			if got.TargetAnsi != tt.want || got.TargetUnicode != tt.want {
+				t.Errorf("Environment() = %q, %q, want %q", got.TargetAnsi, got.TargetUnicode, tt.want)
+			}
+			if got.Mismatch() {
+				t.Errorf("Mismatch() = true, want false")
+			}
+		})
+	}
+
+	diffTests := []struct {
+		ansi, unicode string
+		cp            int
+		want          bool
+	}{
+		{`C:\a.exe`, `C:\a.exe`, 1252, false},
+		{`C:\a.exe`, `C:\b.exe`, 1252, true},
+		{`C:\a.exe`, `C:\a.exe.lnk`, 1252, true},
+		{`C:\?.exe`, `C:\Я.exe`, 1252, false},
+		{`C:\?.exe`, `C:\ü.exe`, 1251, false},
+		{`C:\??.exe`, `C:\文档.exe`, 1251, false},
+		{"C:\\\uFFFD.exe", `C:\Я.exe`, 1252, false},
+		// ü is in 1252, Windows would not replace it.
+		{`C:\?.exe`, `C:\ü.exe`, 1252, true},
+		{`C:\?.exe`, `C:\a.exe`, 1252, true},
+		{`????????`, `C:\a.exe`, 1252, true},
+		{"", `C:\a.exe`, 1252, false},
+	}
+	for _, tt := range diffTests {
+		if got := pathsDiffer(tt.ansi, tt.unicode, tt.cp); got != tt.want {
+			t.Errorf("pathsDiffer(%q, %q, %d) = %t, want %t", tt.ansi, tt.unicode, tt.cp, got, tt.want)
+		}
+	}
+
+	// IconEnvironmentDataBlock has the same layout, no sample has one.
+	f, err := File("test/Windows Store.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range f.DataBlocks.Blocks {
+		if b.Signature != environmentSignature {
+			continue
+		}
+		icon, err := IconEnvironment(b.Data)
+		if err != nil {
+			t.Fatalf("IconEnvironment() error = %v", err)
+		}
+		if icon.TargetUnicode != `%windir%\WinStore\WinStore.htm` || icon.Mismatch() {
+			t.Errorf("IconEnvironment() = %+v, want the sample path", icon)
+		}
+	}
+	data := newTestBlock(environmentDataSize).
+		ansi(0, `%SystemRoot%\notepad.exe`).
+		unicode(260, `%SystemRoot%\calc.exe`)
+	icon, ok := parseBlocks(t, extraData(data.block(0xA0000007)))[0].(IconEnvironmentDataBlock)
+	if !ok {
+		t.Fatalf("block is not an IconEnvironmentDataBlock")
+	}
+	if !icon.Mismatch() || !strings.Contains(icon.String(), "Mismatch") {
+		t.Errorf("IconEnvironment() = %+v, want a mismatch", icon)
+	}
+}
+
+func TestShim(t *testing.T) {
+	tests := []struct {
+		name           string
+		layer          string
+		want           []ShimLayer
+		wantPrivileged bool
+	}{
+		{"version", "WinXPSp3", []ShimLayer{{"WinXPSp3", "Windows XP Service Pack 3", false}}, false},
+		{"privilege", "~ RUNASADMIN DisableThemes", []ShimLayer{
+			{"RUNASADMIN", "Run as administrator, with a UAC prompt", true},
+			{"DisableThemes", "Disable visual themes", false},
+		}, true},
+		{"unknown", "CustomLayer", []ShimLayer{{"CustomLayer", "", false}}, false},
+		{"empty", "", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := newTestBlock(shimDataMinSize).unicode(0, tt.layer)
+			got, ok := parseBlocks(t, extraData(data.block(0xA0000008)))[0].(ShimDataBlock)
+			if !ok {
+				t.Fatalf("block is not a ShimDataBlock")
+			}
+			if got.LayerName != tt.layer || !reflect.DeepEqual(got.Layers, tt.want) {
+				t.Errorf("Shim() = %+v, want %q and %+v", got, tt.layer, tt.want)
+			}
+			if got.Privileged() != tt.wantPrivileged {
+				t.Errorf("Privileged() = %t, want %t", got.Privileged(), tt.wantPrivileged)
+			}
+		})
+	}
+}
+
+func TestFolders(t *testing.T) {
+	k, ok := block(t, "test/test.lnk", knownFolderSignature).(KnownFolderDataBlock)
+	if !ok {
+		t.Fatalf("block is not a KnownFolderDataBlock")
+	}
+	want := KnownFolderDataBlock{
+		KnownFolderID: mustGUID(t, "{F3CE0F7C-4901-4ACC-8648-D5D44B04EF8F}"),
+		Offset:        0x3A,
+		FolderID:      "FOLDERID_UsersFiles",
+		Name:          "Users Files",
+		ItemIndex:     1,
+	}
+	if k != want {
+		t.Errorf("KnownFolder() = %+v, want %+v", k, want)
+	}
+
+	tests := []struct {
+		name      string
+		id        uint32
+		wantCSIDL string
+		wantName  string
+	}{
+		{"documents", 0x05, "CSIDL_PERSONAL", "My Documents"},
+		{"program-files", 0x26, "CSIDL_PROGRAM_FILES", "Program Files"},
+		{"unknown", 0x3F, "", "0x3f"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := append(uint32Byte(tt.id), uint32Byte(0x14)...)
+			got, err := SpecialFolder(data)
+			if err != nil {
+				t.Fatalf("SpecialFolder() error = %v", err)
+			}
+			if got.CSIDL != tt.wantCSIDL || got.Name != tt.wantName || got.Offset != 0x14 {
+				t.Errorf("SpecialFolder() = %+v, want %s and %s", got, tt.wantCSIDL, tt.wantName)
+			}
+			// The offset is only resolved by Read.
+			if got.ItemIndex != -1 {
+				t.Errorf("SpecialFolder() ItemIndex = %d, want -1", got.ItemIndex)
+			}
+		})
+	}
+}
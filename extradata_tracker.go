@@ -0,0 +1,90 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// TrackerDataBlock (section 2.5.10) contains data used to find the link
+// target with the Link Tracking service. Signature 0xA0000003.
+type TrackerDataBlock struct {
+	// Size of the rest of the block. Must be 0x58.
+	Length uint32
+
+	// Must be zero.
+	Version uint32
+
+	// NetBIOS name of the machine where the link target was last known to
+	// reside. 16 bytes on disk, null-terminated.
+	MachineID string
+
+	// Droid is the volume and object ID of the target, used by the Link
+	// Tracking service.
+	DroidVolumeID GUID
+	DroidFileID   GUID
+
+	// DroidBirth is the volume and object ID when the target was created.
+	DroidBirthVolumeID GUID
+	DroidBirthFileID   GUID
+
+	// Timestamp, clock sequence and MAC address decoded from DroidFileID and
+	// DroidBirthFileID. Zero if they are not version 1 UUIDs.
+	FileID      UUIDv1
+	BirthFileID UUIDv1
+}
+
+// trackerDataSize is the size of TrackerDataBlock after the signature.
+const trackerDataSize = 0x58
+
+// Tracker parses the data of a TrackerDataBlock. data is ExtraDataBlock.Data
+// (everything after the signature).
+func Tracker(data []byte) (t TrackerDataBlock, err error) {
+	if len(data) < trackerDataSize {
+		return t, fmt.Errorf("golnk.Tracker: invalid size - got %d bytes, want %d", len(data), trackerDataSize)
+	}
+	r := bytes.NewReader(data)
+
+	// All fields are fixed size and we have checked the size, these reads
+	// cannot fail.
+	binary.Read(r, binary.LittleEndian, &t.Length)
+	binary.Read(r, binary.LittleEndian, &t.Version)
+
+	var machineID [16]byte
+	binary.Read(r, binary.LittleEndian, &machineID)
+	t.MachineID = readString(machineID[:])
+
+	binary.Read(r, binary.LittleEndian, &t.DroidVolumeID)
+	binary.Read(r, binary.LittleEndian, &t.DroidFileID)
+	binary.Read(r, binary.LittleEndian, &t.DroidBirthVolumeID)
+	binary.Read(r, binary.LittleEndian, &t.DroidBirthFileID)
+
+	t.FileID, _ = t.DroidFileID.UUIDv1()
+	t.BirthFileID, _ = t.DroidBirthFileID.UUIDv1()
+	return t, nil
+}
+
+// String prints the TrackerDataBlock in a table.
+func (t TrackerDataBlock) String() string {
+	rows := [][]string{
+		{"Length", uint32TableStr(t.Length)},
+		{"Version", uint32Str(t.Version)},
+		{"MachineID", t.MachineID},
+		{"DroidVolumeID", t.DroidVolumeID.String()},
+		{"DroidFileID", t.DroidFileID.String()},
+	}
+	if !t.FileID.Time.IsZero() {
+		rows = append(rows, []string{"FileID Time", t.FileID.Time.String()})
+		rows = append(rows, []string{"FileID ClockSequence", uint16Str(t.FileID.ClockSequence)})
+		rows = append(rows, []string{"FileID MAC", t.FileID.Node})
+	}
+	rows = append(rows, []string{"DroidBirthVolumeID", t.DroidBirthVolumeID.String()})
+	rows = append(rows, []string{"DroidBirthFileID", t.DroidBirthFileID.String()})
+	if !t.BirthFileID.Time.IsZero() {
+		rows = append(rows, []string{"BirthFileID Time", t.BirthFileID.Time.String()})
+		rows = append(rows, []string{"BirthFileID ClockSequence", uint16Str(t.BirthFileID.ClockSequence)})
+		rows = append(rows, []string{"BirthFileID MAC", t.BirthFileID.Node})
+	}
+
+	return blockTable("TrackerDataBlock", rows)
+}
@@ -1,8 +1,10 @@
 package lnk
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 )
 
@@ -13,51 +15,231 @@ type LnkFile struct {
 	LinkInfo   LinkInfoSection         // LinkInfo.
 	StringData StringDataSection       // StringData.
 	DataBlocks ExtraDataSection        // ExtraData blocks.
+	Overlay    Overlay                 // Data after the ExtraData section, see WithOverlay.
+
+	// CodePage is the Windows code page used to decode the ANSI strings.
+	CodePage int
+
+	// Warnings are the errors skipped in lenient mode and the invalid UTF-16
+	// strings, which are kept with U+FFFD in both modes.
+	Warnings []ParseWarning
 }
 
-// Read parses an io.Reader pointing to the contents of an lnk file.
-func Read(r io.Reader, maxSize uint64) (f LnkFile, err error) {
+// Read parses an io.Reader pointing to the contents of an lnk file. Parse
+// errors wrap a *ParseError with the offset from the start of the file, use
+// errors.As and errors.Is with the Err* sentinels to check them. Use
+// WithLimits to bound the memory used for untrusted files.
+//
+// Read stops at the ExtraData TerminalBlock unless WithOverlay is passed.
+func Read(r io.Reader, maxSize uint64, opts ...Option) (f LnkFile, err error) {
+	o := newOptions(opts)
+	if !validCodePage(o.codePage) {
+		return f, fmt.Errorf("golnk.Read: unsupported code page %d", o.codePage)
+	}
+
+	b := newBudget(o.limits)
 
-	f.Header, err = Header(r, maxSize)
+	if o.lenient {
+		// Read one more byte than the limit to find larger files.
+		data, err := ioutil.ReadAll(io.LimitReader(r, b.limits.MaxAlloc+1))
+		if err != nil {
+			return f, fmt.Errorf("golnk.Read: read data - %w", err)
+		}
+		// The input is only checked against MaxAlloc, the budget counts the
+		// sections parsed from it.
+		if int64(len(data)) > b.limits.MaxAlloc {
+			return f, fmt.Errorf("golnk.Read: read data - %w - file is larger than %d bytes",
+				ErrLimitExceeded, b.limits.MaxAlloc)
+		}
+		if f, err = readLenient(data, maxSize, b); err != nil {
+			return f, err
+		}
+		// The overlay is only known if the TerminalBlock was found.
+		if end := f.DataBlocks.Span.End(); o.overlay && f.DataBlocks.Span.Size > 0 {
+			var s overlayScanner
+			s.Write(data[end:])
+			f.Overlay = s.overlay(end)
+		}
+		f.Warnings = append(f.Warnings, b.warnings...)
+		f.decodeStrings(o)
+		f.resolveFolders()
+		return f, nil
+	}
+
+	// Count the bytes to make the offsets in errors absolute.
+	cr := &countingReader{r: r}
+
+	f.Header, err = Header(cr, maxSize)
 	if err != nil {
-		return f, fmt.Errorf("golnk.Read: parse Header - %s", err.Error())
+		return f, fmt.Errorf("golnk.Read: parse Header - %w", err)
 	}
 
 	// If HasLinkTargetIDList is set, header is immediately followed by a LinkTargetIDList.
 	if f.Header.LinkFlags["HasLinkTargetIDList"] {
-		f.IDList, err = LinkTarget(r)
+		base, n := cr.n, len(b.warnings)
+		f.IDList, err = linkTarget(cr, b)
 		if err != nil {
-			return f, fmt.Errorf("golnk.Read: parse LinkTarget - %s", err.Error())
+			return f, fmt.Errorf("golnk.Read: parse LinkTarget - %w", addOffset(err, base))
 		}
+		f.IDList.shift(base)
+		b.shiftWarnings(n, base)
 	}
 
 	// If HasLinkInfo is set, read LinkInfo section.
 	if f.Header.LinkFlags["HasLinkInfo"] {
-		f.LinkInfo, err = LinkInfo(r, maxSize)
+		base, n := cr.n, len(b.warnings)
+		f.LinkInfo, err = linkInfo(cr, maxSize, b)
 		if err != nil {
-			return f, fmt.Errorf("golnk.Read: parse LinkInfo - %s", err.Error())
+			return f, fmt.Errorf("golnk.Read: parse LinkInfo - %w", addOffset(err, base))
 		}
+		f.LinkInfo.shift(base)
+		b.shiftWarnings(n, base)
 	}
 
 	// Read StringData section.
-	f.StringData, err = StringData(r, f.Header.LinkFlags)
+	base, n := cr.n, len(b.warnings)
+	f.StringData, err = stringData(cr, f.Header.LinkFlags, b)
 	if err != nil {
-		return f, fmt.Errorf("golnk.Read: parse StringData - %s", err.Error())
+		return f, fmt.Errorf("golnk.Read: parse StringData - %w", addOffset(err, base))
 	}
+	f.StringData.Span.shift(base)
+	b.shiftWarnings(n, base)
 
-	f.DataBlocks, err = DataBlock(r)
+	base, n = cr.n, len(b.warnings)
+	f.DataBlocks, err = dataBlock(cr, b)
 	if err != nil {
-		return f, fmt.Errorf("golnk.Read: parse ExtraDataBlock - %s", err.Error())
+		return f, fmt.Errorf("golnk.Read: parse ExtraDataBlock - %w", addOffset(err, base))
 	}
+	f.DataBlocks.shift(base)
+	b.shiftWarnings(n, base)
 
+	if o.overlay {
+		var rest uint64
+		if maxSize > uint64(cr.n) {
+			rest = maxSize - uint64(cr.n)
+		}
+		f.Overlay, err = readOverlay(cr, cr.n, rest)
+		if err != nil {
+			return f, fmt.Errorf("golnk.Read: read overlay - %w", err)
+		}
+	}
+
+	f.Warnings = b.warnings
+	f.decodeStrings(o)
+	f.resolveFolders()
 	return f, err
 }
 
-// File parses an lnk File.
-func File(filename string) (f LnkFile, err error) {
+// decodeStrings decodes the ANSI strings with the code page in o or the
+// detected one.
+func (f *LnkFile) decodeStrings(o options) {
+	cp := o.codePage
+	if cp == CodePageAuto {
+		cp = f.detectCodePage()
+	}
+	f.setCodePage(cp)
+}
+
+// ansiStrings returns the ANSI strings of the file that have a Unicode version
+// and the bytes of all ANSI strings.
+func (f LnkFile) ansiStrings() (pairs []ansiPair, samples [][]byte) {
+	li := f.LinkInfo
+	nl := li.NetworkRelativeLink
+	pairs = []ansiPair{
+		{li.LocalBasePathRaw, li.LocalBasePathUnicode},
+		{li.CommonPathSuffixRaw, li.CommonPathSuffixUnicode},
+		{nl.NetNameRaw, nl.NetNameUnicode},
+		{nl.DeviceNameRaw, nl.DeviceNameUnicode},
+	}
+	if env, ok := f.environment(); ok {
+		pairs = append(pairs, ansiPair{env.TargetAnsiRaw, env.TargetUnicode})
+	}
+
+	st := f.StringData
+	samples = [][]byte{li.VolID.VolumeLabelRaw, st.NameStringRaw, st.RelativePathRaw,
+		st.WorkingDirRaw, st.CommandLineArgumentsRaw, st.IconLocationRaw}
+	for _, p := range pairs {
+		samples = append(samples, p.raw)
+	}
+	return pairs, samples
+}
+
+// detectCodePage returns the code page of the ANSI strings in the file.
+func (f LnkFile) detectCodePage() int {
+	return detectCodePage(f.ansiStrings())
+}
+
+// setCodePage decodes all ANSI strings again with the code page.
+func (f *LnkFile) setCodePage(cp int) {
+	f.CodePage = cp
+	f.LinkInfo.setCodePage(cp)
+	f.StringData.setCodePage(cp)
+	f.DataBlocks.setCodePage(cp)
+	for i, it := range f.IDList.List.ItemIDList {
+		// Invalid UTF-16 names were added to the warnings by linkTarget.
+		f.IDList.List.ItemIDList[i].Item = parseShellItem(it.Data, &itemDecoder{cp: cp})
+	}
+}
+
+// MarshalBinary returns the lnk file as it appears on disk. Sections are
+// written based on the header's LinkFlags, the same way Read parses them.
+func (f LnkFile) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	head, err := f.Header.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("golnk.MarshalBinary: Header - %w", err)
+	}
+	buf.Write(head)
+
+	if f.Header.LinkFlags["HasLinkTargetIDList"] {
+		idList, err := f.IDList.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("golnk.MarshalBinary: LinkTarget - %w", err)
+		}
+		buf.Write(idList)
+	}
+
+	if f.Header.LinkFlags["HasLinkInfo"] {
+		info, err := f.LinkInfo.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("golnk.MarshalBinary: LinkInfo - %w", err)
+		}
+		buf.Write(info)
+	}
+
+	st, err := f.StringData.Marshal(f.Header.LinkFlags)
+	if err != nil {
+		return nil, fmt.Errorf("golnk.MarshalBinary: StringData - %w", err)
+	}
+	buf.Write(st)
+
+	extra, err := f.DataBlocks.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("golnk.MarshalBinary: ExtraDataBlock - %w", err)
+	}
+	buf.Write(extra)
+
+	return buf.Bytes(), nil
+}
+
+// Write serializes an LnkFile and writes it to an io.Writer.
+func Write(w io.Writer, f LnkFile) error {
+	b, err := f.MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("golnk.Write: %w", err)
+	}
+	if _, err = w.Write(b); err != nil {
+		return fmt.Errorf("golnk.Write: write - %w", err)
+	}
+	return nil
+}
+
+// File parses an lnk File. The Overlay is read, see WithOverlay.
+func File(filename string, opts ...Option) (f LnkFile, err error) {
 	fi, err := os.Open(filename)
 	if err != nil {
-		return f, fmt.Errorf("golnk.File: open file - %s", err.Error())
+		return f, fmt.Errorf("golnk.File: open file - %w", err)
 	}
 	defer fi.Close()
 
@@ -70,5 +252,6 @@ func File(filename string) (f LnkFile, err error) {
 		}
 	}
 
-	return Read(fi, maxSize)
+	// The file is not used after parsing, the overlay can be read.
+	return Read(fi, maxSize, append([]Option{WithOverlay()}, opts...)...)
 }
@@ -0,0 +1,71 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"testing"
+)
+
+// samples are the lnk files in the test directory.
+var samples = []string{
+	"test/Visual Studio Code.lnk",
+	"test/Windows Store.lnk",
+	"test/nem.test",
+	"test/remote.directory.xp.test",
+	"test/remote.file.xp.test",
+	"test/test-orig.lnk",
+	"test/test.lnk",
+	"test/test.lnk.bak",
+	"test/vbox-svr-win10.lnk",
+}
+
+func TestWrite(t *testing.T) {
+	for _, name := range samples {
+		t.Run(name, func(t *testing.T) {
+			want, err := ioutil.ReadFile(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := Read(bytes.NewReader(want), uint64(len(want)))
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			var got bytes.Buffer
+			if err := Write(&got, f); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("Write() round trip mismatch\ngot:\n%s\nwant:\n%s", hex.Dump(got.Bytes()), hex.Dump(want))
+			}
+		})
+	}
+}
+
+// TestWriteHeader checks that header values that do not map back from their
+// strings survive a round trip.
+func TestWriteHeader(t *testing.T) {
+	want, err := ioutil.ReadFile("test/test.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want[0x17] |= 0x80 // undefined LinkFlags bit31
+	want[0x3C] = 0x02  // ShowCommand SW_SHOWMINIMIZED, read as SW_SHOWNORMAL
+	want[0x40] = 0x41  // HotKey CTRL+ALT+A
+	want[0x41] = 0x06
+
+	f, err := Read(bytes.NewReader(want), uint64(len(want)))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if f.Header.HotKey != "CTRL+ALT+A" {
+		t.Errorf("HotKey = %q, want %q", f.Header.HotKey, "CTRL+ALT+A")
+	}
+	got, err := f.Header.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	if !bytes.Equal(got, want[:headerSize]) {
+		t.Errorf("MarshalBinary() mismatch\ngot:\n%s\nwant:\n%s", hex.Dump(got), hex.Dump(want[:headerSize]))
+	}
+}
@@ -0,0 +1,170 @@
+package lnk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+// The fuzz targets check that parsing never panics. Inputs that panicked are
+// kept in testdata/fuzz and run with go test.
+
+// readSamples parses the samples. The seeds of the section targets are taken
+// from the sections of the samples.
+func readSamples(f *testing.F) (data [][]byte, files []LnkFile) {
+	for _, name := range samples {
+		b, err := ioutil.ReadFile(name)
+		if err != nil {
+			f.Fatal(err)
+		}
+		lnk, err := Read(bytes.NewReader(b), uint64(len(b)))
+		if err != nil {
+			f.Fatalf("%s: %v", name, err)
+		}
+		data = append(data, b)
+		files = append(files, lnk)
+	}
+	return data, files
+}
+
+func FuzzRead(f *testing.F) {
+	data, _ := readSamples(f)
+	for _, b := range data {
+		f.Add(b, false)
+	}
+	f.Fuzz(func(t *testing.T, b []byte, lenient bool) {
+		opts := []Option{WithLimits(Limits{MaxAlloc: 1 << 20})}
+		if lenient {
+			opts = append(opts, Lenient())
+		}
+		lnk, err := Read(bytes.NewReader(b), uint64(len(b)), opts...)
+		if err != nil && !lenient {
+			return
+		}
+		// Use the result like a caller would.
+		_ = lnk.Header.String()
+		_ = lnk.IDList.String()
+		_ = lnk.LinkInfo.String()
+		_ = lnk.StringData.String()
+		_ = lnk.DataBlocks.String()
+		_ = lnk.Target()
+		_ = lnk.Validate()
+		_, _ = lnk.MarshalBinary()
+	})
+}
+
+func FuzzHeader(f *testing.F) {
+	data, _ := readSamples(f)
+	for _, b := range data {
+		f.Add(b[:headerSize])
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		head, err := Header(bytes.NewReader(b), uint64(len(b)))
+		if err == nil {
+			_ = head.String()
+		}
+	})
+}
+
+func FuzzLinkTarget(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		b, _ := lnk.IDList.MarshalBinary()
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		li, _ := LinkTarget(bytes.NewReader(b))
+		_ = li.String()
+		_ = li.Path()
+	})
+}
+
+func FuzzLinkInfo(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		f.Add(lnk.LinkInfo.Raw)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		info, err := LinkInfo(bytes.NewReader(b), uint64(len(b)))
+		if err == nil {
+			_ = info.String()
+			_ = info.Dump()
+		}
+	})
+}
+
+func FuzzVolumeID(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		b, _ := lnk.LinkInfo.VolID.MarshalBinary()
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		v, err := VolumeID(bytes.NewReader(b), uint64(len(b)))
+		if err == nil {
+			_ = v.String()
+		}
+	})
+}
+
+func FuzzCommonNetwork(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		b, _ := lnk.LinkInfo.NetworkRelativeLink.MarshalBinary()
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		c, err := CommonNetwork(bytes.NewReader(b), uint64(len(b)))
+		if err == nil {
+			_ = c.String()
+		}
+	})
+}
+
+func FuzzStringData(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		b, _ := lnk.StringData.Marshal(lnk.Header.LinkFlags)
+		f.Add(b, flagValue(lnk.Header.LinkFlags, linkFlags))
+	}
+	f.Fuzz(func(t *testing.T, b []byte, flags uint32) {
+		st, err := StringData(bytes.NewReader(b), matchFlag(flags, linkFlags))
+		if err == nil {
+			_ = st.String()
+		}
+	})
+}
+
+func FuzzDataBlock(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		b, _ := lnk.DataBlocks.MarshalBinary()
+		f.Add(b)
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		extra, _ := DataBlock(bytes.NewReader(b))
+		_ = extra.String()
+		for _, db := range extra.Blocks {
+			_ = db.Dump()
+			if db.Parsed != nil {
+				_ = db.Parsed.String()
+			}
+		}
+	})
+}
+
+func FuzzParseShellItem(f *testing.F) {
+	_, files := readSamples(f)
+	for _, lnk := range files {
+		for _, it := range lnk.IDList.List.ItemIDList {
+			f.Add(it.Data)
+		}
+	}
+	f.Fuzz(func(t *testing.T, b []byte) {
+		// Empty data returns nil.
+		if it := ParseShellItem(b); it != nil {
+			_ = it.Name()
+			_ = shellItemStr(it)
+		}
+	})
+}
@@ -1,5 +1,10 @@
 module github.com/parsiya/golnk
 
-go 1.13
+go 1.18
 
-require github.com/olekukonko/tablewriter v0.0.5
+require (
+	github.com/olekukonko/tablewriter v0.0.5
+	golang.org/x/text v0.13.0
+)
+
+require github.com/mattn/go-runewidth v0.0.9 // indirect
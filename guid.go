@@ -0,0 +1,87 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// GUID is a 16-byte GUID/CLSID as it appears on disk. The first three parts
+// are little-endian.
+type GUID [16]byte
+
+// String returns the GUID in registry format, e.g.
+// {00021401-0000-0000-C000-000000000046}.
+func (g GUID) String() string {
+	return fmt.Sprintf("{%08X-%04X-%04X-%X-%X}",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8:10], g[10:16])
+}
+
+// MarshalText returns the String representation so GUIDs are readable in
+// JSON.
+func (g GUID) MarshalText() ([]byte, error) {
+	return []byte(g.String()), nil
+}
+
+// parseGUID converts a GUID in registry format (braces are optional) to a
+// GUID. It's the reverse of GUID.String.
+func parseGUID(s string) (g GUID, err error) {
+	s = strings.Trim(s, "{}")
+	b, err := hex.DecodeString(strings.Replace(s, "-", "", -1))
+	if err != nil || len(b) != 16 || len(s) != 36 {
+		return g, fmt.Errorf("golnk.parseGUID: invalid GUID - got %s", s)
+	}
+	// The first three parts are stored little-endian.
+	binary.LittleEndian.PutUint32(g[0:4], binary.BigEndian.Uint32(b[0:4]))
+	binary.LittleEndian.PutUint16(g[4:6], binary.BigEndian.Uint16(b[4:6]))
+	binary.LittleEndian.PutUint16(g[6:8], binary.BigEndian.Uint16(b[6:8]))
+	copy(g[8:], b[8:])
+	return g, nil
+}
+
+// UUIDv1 contains the fields of a version 1 (time-based) UUID. Distributed
+// link tracking object IDs are version 1 UUIDs created on the machine.
+type UUIDv1 struct {
+	// Time the UUID was created.
+	Time time.Time
+	// ClockSequence is 14 bits and changes if the clock goes backwards or the
+	// node changes.
+	ClockSequence uint16
+	// Node is usually the MAC address of the machine, e.g. 08:00:27:9b:1c:c3.
+	Node string
+}
+
+// uuidEpoch is the number of 100-nanosecond intervals between the start of the
+// UUID time (1582-10-15) and the Unix epoch.
+const uuidEpoch = 0x01B21DD213814000
+
+// Version returns the version of the UUID from the four most significant bits
+// of the third part.
+func (g GUID) Version() int {
+	return int(binary.LittleEndian.Uint16(g[6:8]) >> 12)
+}
+
+// UUIDv1 decodes the timestamp, clock sequence and node of a version 1 UUID.
+// ok is false for other versions.
+func (g GUID) UUIDv1() (u UUIDv1, ok bool) {
+	if g.Version() != 1 {
+		return u, false
+	}
+	timeLow := uint64(binary.LittleEndian.Uint32(g[0:4]))
+	timeMid := uint64(binary.LittleEndian.Uint16(g[4:6]))
+	timeHigh := uint64(binary.LittleEndian.Uint16(g[6:8]) & 0x0FFF)
+	ts := timeHigh<<48 | timeMid<<32 | timeLow
+
+	// Convert to 100-nanosecond intervals since the Unix epoch first, then to
+	// seconds and nanoseconds separately to avoid overflowing int64.
+	unix := int64(ts) - uuidEpoch
+	u.Time = time.Unix(unix/1e7, (unix%1e7)*100).UTC()
+	u.ClockSequence = uint16(g[8]&0x3F)<<8 | uint16(g[9])
+	u.Node = fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", g[10], g[11], g[12], g[13], g[14], g[15])
+	return u, true
+}
@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -40,6 +41,7 @@ type ShellLinkHeaderSection struct {
 	Reserved2      uint32    // Zero.
 	Reserved3      uint32    // Zero.
 	Raw            []byte    // Section's raw bytes.
+	Span           Span      // Location of the section.
 }
 
 // linkFlags defines what shell link structures are in the file.
@@ -93,34 +95,44 @@ var fileAttributesFlags = []string{
 }
 
 // Header parses the first 0x4c bytes of the io.Reader and returns a ShellLinkHeader.
+// The header has a fixed size so maxSize is not used. Errors are *ParseError.
 func Header(r io.Reader, maxSize uint64) (head ShellLinkHeaderSection, err error) {
 
-	// Read the section.
-	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize)
+	// Check the magic before reading the rest so files that are not lnk files
+	// return ErrBadMagic.
+	err = binary.Read(r, binary.LittleEndian, &head.Magic)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: error reading magic string - %s", err.Error())
+		return head, headerError("HeaderSize", 0x00, err)
+	}
+	if head.Magic != headerSize {
+		return head, headerError("HeaderSize", 0x00,
+			fmt.Errorf("%w - got 0x%X, want 0x4C", ErrBadMagic, head.Magic))
 	}
 
-	// Check sectionSize (the first four bytes) against 0x4c.
-	if sectionSize != headerSize {
-		return head, fmt.Errorf("lnk.header: invalid magic string - got %x, want %s", sectionSize, "0x4C")
+	// Read the rest of the section. If the data is truncated, the fields are
+	// read from what is there to find the first missing one.
+	sectionData := make([]byte, headerSize)
+	copy(sectionData, uint32Byte(head.Magic))
+	n, err := io.ReadFull(r, sectionData[4:])
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return head, headerError("LinkCLSID", 0x04, err)
 	}
-	head.Magic = uint32(sectionSize)
+	sectionReader := bytes.NewReader(sectionData[4 : 4+n])
 
 	// Store raw bytes.
-	head.Raw = sectionData
+	head.Raw = sectionData[:4+n]
 
 	// Next 16 bytes should be 00021401-0000-0000-C000-000000000046.
 	// When reading, we will see 0114020000000000c000000000000046.
 	var clsID [16]byte
 	err = binary.Read(sectionReader, binary.LittleEndian, &clsID)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: error reading LinkCLSID - %s", err.Error())
+		return head, headerError("LinkCLSID", 0x04, err)
 	}
 	hexClsID := hex.EncodeToString(clsID[:])
 	if hexClsID != classID {
-		return head,
-			fmt.Errorf("lnk.header: invalid class ID - got %s, want %s", hexClsID, classID)
+		return head, headerError("LinkCLSID", 0x04,
+			fmt.Errorf("%w - got %s, want %s", ErrBadCLSID, hexClsID, classID))
 	}
 	head.LinkCLSID = clsID
 
@@ -130,7 +142,7 @@ func Header(r io.Reader, maxSize uint64) (head ShellLinkHeaderSection, err error
 	var lf uint32
 	err = binary.Read(sectionReader, binary.LittleEndian, &lf)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading LinkFlags - %s", err.Error())
+		return head, headerError("LinkFlags", 0x14, err)
 	}
 	head.LinkFlags = matchFlag(lf, linkFlags)
 
@@ -139,47 +151,48 @@ func Header(r io.Reader, maxSize uint64) (head ShellLinkHeaderSection, err error
 	// Same as before, read BigEndian.
 	err = binary.Read(sectionReader, binary.LittleEndian, &attribs)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading FileAttributes - %s", err.Error())
+		return head, headerError("FileAttributes", 0x18, err)
 	}
 	head.FileAttributes = matchFlag(attribs, fileAttributesFlags)
 
 	// Convert timestamps from Windows Filetime to time.Time.
+	// On disk the order is CreationTime, AccessTime and WriteTime.
 	var crTime, wrTime, acTime [8]byte
 	err = binary.Read(sectionReader, binary.LittleEndian, &crTime)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading CreationTime - %s", err.Error())
+		return head, headerError("CreationTime", 0x1C, err)
 	}
 	head.CreationTime = toTime(crTime)
 
-	err = binary.Read(sectionReader, binary.LittleEndian, &wrTime)
+	err = binary.Read(sectionReader, binary.LittleEndian, &acTime)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading WriteTime - %s", err.Error())
+		return head, headerError("AccessTime", 0x24, err)
 	}
-	head.WriteTime = toTime(wrTime)
+	head.AccessTime = toTime(acTime)
 
-	err = binary.Read(sectionReader, binary.LittleEndian, &acTime)
+	err = binary.Read(sectionReader, binary.LittleEndian, &wrTime)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading AccessTime - %s", err.Error())
+		return head, headerError("WriteTime", 0x2C, err)
 	}
-	head.AccessTime = toTime(acTime)
+	head.WriteTime = toTime(wrTime)
 
 	// Target file size.
 	err = binary.Read(sectionReader, binary.LittleEndian, &head.TargetFileSize)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading target file size - %s", err.Error())
+		return head, headerError("FileSize", 0x34, err)
 	}
 
 	// Icon index is a signed 32-bit integer.
 	err = binary.Read(sectionReader, binary.LittleEndian, &head.IconIndex)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading icon index - %s", err.Error())
+		return head, headerError("IconIndex", 0x38, err)
 	}
 
 	// ShowCommand
 	var sw uint32
 	err = binary.Read(sectionReader, binary.LittleEndian, &sw)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading showcommand - %s", err.Error())
+		return head, headerError("ShowCommand", 0x3C, err)
 	}
 	head.ShowCommand = showCommand(sw)
 
@@ -187,16 +200,78 @@ func Header(r io.Reader, maxSize uint64) (head ShellLinkHeaderSection, err error
 	var hk uint16
 	err = binary.Read(sectionReader, binary.LittleEndian, &hk)
 	if err != nil {
-		return head, fmt.Errorf("lnk.header: reading hotkey - %s", err.Error())
+		return head, headerError("HotKey", 0x40, err)
 	}
 	head.HotKey = HotKey(hk)
 
 	// The rest should be 10 0x00 bytes.
+	if n < headerSize-4 {
+		return head, headerError("Reserved1", 0x42, ErrTruncated)
+	}
 	binary.Read(sectionReader, binary.LittleEndian, &head.Reserved1)
 	binary.Read(sectionReader, binary.LittleEndian, &head.Reserved2)
 	binary.Read(sectionReader, binary.LittleEndian, &head.Reserved3)
 
-	return head, err
+	head.Span = Span{Size: headerSize}
+	return head, nil
+}
+
+// headerError returns a *ParseError for a ShellLinkHeader field.
+func headerError(field string, offset int64, err error) error {
+	return parseError("ShellLinkHeader", field, offset, err)
+}
+
+// MarshalBinary returns the 0x4C bytes of the ShellLinkHeader as they appear
+// on disk. The bytes are created from the fields. If the header was read from
+// a file, the values in Raw that the fields cannot hold (undefined flag bits
+// and ShowCommand or HotKey values that map to the same string) are kept.
+func (h ShellLinkHeaderSection) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+
+	flags := flagValue(h.LinkFlags, linkFlags)
+	attribs := flagValue(h.FileAttributes, fileAttributesFlags)
+	show := showCommandValue(h.ShowCommand)
+	hotKey := hotKeyValue(h.HotKey)
+	if len(h.Raw) >= headerSize {
+		flags |= binary.LittleEndian.Uint32(h.Raw[0x14:]) &^ (1<<uint(len(linkFlags)) - 1)
+		attribs |= binary.LittleEndian.Uint32(h.Raw[0x18:]) &^ (1<<uint(len(fileAttributesFlags)) - 1)
+		if raw := binary.LittleEndian.Uint32(h.Raw[0x3C:]); showCommand(raw) == h.ShowCommand {
+			show = raw
+		}
+		if raw := binary.LittleEndian.Uint16(h.Raw[0x40:]); HotKey(raw) == h.HotKey {
+			hotKey = raw
+		}
+	}
+
+	buf.Write(uint32Byte(headerSize))
+
+	// Use the only valid class ID if LinkCLSID was never populated.
+	clsID := h.LinkCLSID
+	if clsID == ([16]byte{}) {
+		b, _ := hex.DecodeString(classID)
+		copy(clsID[:], b)
+	}
+	buf.Write(clsID[:])
+
+	buf.Write(uint32Byte(flags))
+	buf.Write(uint32Byte(attribs))
+
+	crTime := fromTime(h.CreationTime)
+	acTime := fromTime(h.AccessTime)
+	wrTime := fromTime(h.WriteTime)
+	buf.Write(crTime[:])
+	buf.Write(acTime[:])
+	buf.Write(wrTime[:])
+
+	buf.Write(uint32Byte(h.TargetFileSize))
+	buf.Write(uint32Byte(uint32(h.IconIndex)))
+	buf.Write(uint32Byte(show))
+	buf.Write(uint16Byte(hotKey))
+	buf.Write(uint16Byte(h.Reserved1))
+	buf.Write(uint32Byte(h.Reserved2))
+	buf.Write(uint32Byte(h.Reserved3))
+
+	return buf.Bytes(), nil
 }
 
 // String prints the ShellLinkHeader in a table.
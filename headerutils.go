@@ -29,6 +29,18 @@ func showCommand(s uint32) string {
 	return "SW_SHOWNORMAL"
 }
 
+// showCommandValue is the reverse of showCommand and returns the uint32 stored
+// on disk for a ShowCommand string. Unknown strings become SW_SHOWNORMAL.
+func showCommandValue(s string) uint32 {
+	switch s {
+	case "SW_SHOWMAXIMIZED":
+		return 0x03
+	case "SW_SHOWMINNOACTIVE":
+		return 0x07
+	}
+	return 0x01
+}
+
 /*
 	HotKeyFlags contains the hotkey.
 	Although it's 4 bytes, only the first 2 bytes are used.
@@ -38,35 +50,37 @@ func showCommand(s uint32) string {
 	If between 0x70 and 0x87 it's F(num-0x70+1) (e.g. 0x70 == F1 and 0x87 == F24).
 	0x90 == NUM LOCK and 0x91 SCROLL LOCK.
 
-	Second byte is HighByte.
+	Second byte is HighByte, a combination of:
 	0x01: SHIFT
 	0X02: CTRL
-	0X03: ALT
+	0X04: ALT
 */
-// HotKey returns the string representation of the hotkey uint32.
+
+// hotKeyModifiers are the HighByte bits in the order they are printed.
+var hotKeyModifiers = []string{"SHIFT", "CTRL", "ALT"}
+
+// HotKey returns the string representation of the hotkey uint32, e.g.
+// "CTRL+F12" or "CTRL+ALT+A".
 func HotKey(hotkey uint16) string {
 	var sb strings.Builder
 	lb := byteMaskuint16(hotkey, 0) // first byte
 	hb := byteMaskuint16(hotkey, 1) // second byte
 
-	switch hb {
-	case 0x01:
-		sb.WriteString("SHIFT")
-	case 0x02:
-		sb.WriteString("CTRL")
-	case 0x04:
-		sb.WriteString("ALT")
-	default:
-		// 0x00 is technically "no key assigned", but any value other than these
-		// is the same.
+	// 0x00 is technically "no key assigned", but any value with other bits
+	// is the same.
+	if hb == 0 || hb>>uint(len(hotKeyModifiers)) != 0 {
 		return "No Key Assigned"
 	}
-
-	sb.WriteString("+")
+	for i, m := range hotKeyModifiers {
+		if hb&(1<<uint(i)) != 0 {
+			sb.WriteString(m)
+			sb.WriteString("+")
+		}
+	}
 
 	switch {
 	case 0x30 <= lb && lb <= 0x5A:
-		sb.WriteString(string(lb))
+		sb.WriteString(string(rune(lb)))
 	case 0x70 <= lb && lb <= 0x87:
 		sb.WriteString("F" + strconv.Itoa(int(lb-0x70+1)))
 	case lb == 0x90:
@@ -81,8 +95,57 @@ func HotKey(hotkey uint16) string {
 	return sb.String()
 }
 
+// hotKeyValue is the reverse of HotKey and returns the uint16 HotKeyFlags for
+// a string such as "CTRL+F12" or "CTRL+ALT+A". Anything HotKey cannot produce
+// is 0x0000 ("No Key Assigned").
+func hotKeyValue(s string) uint16 {
+	parts := strings.Split(s, "+")
+	if len(parts) < 2 {
+		return 0
+	}
+
+	var hb uint16
+	for _, p := range parts[:len(parts)-1] {
+		bit := uint16(0)
+		for i, m := range hotKeyModifiers {
+			if p == m {
+				bit = 1 << uint(i)
+			}
+		}
+		if bit == 0 || hb&bit != 0 {
+			return 0
+		}
+		hb |= bit
+	}
+
+	var lb uint16
+	key := parts[len(parts)-1]
+	switch {
+	case len(key) == 1 && 0x30 <= key[0] && key[0] <= 0x5A:
+		lb = uint16(key[0])
+	case key == "NUM LOCK":
+		lb = 0x90
+	case key == "SCROLL LOCK":
+		lb = 0x91
+	case strings.HasPrefix(key, "F"):
+		n, err := strconv.Atoi(key[1:])
+		if err != nil || n < 1 || n > 24 {
+			return 0
+		}
+		lb = uint16(0x70 + n - 1)
+	default:
+		return 0
+	}
+	return hb<<8 | lb
+}
+
 // toTime converts an 8-byte Windows Filetime to time.Time.
 func toTime(t [8]byte) time.Time {
+	// A zero Filetime means the value is not set. Converting it would overflow
+	// int64 nanoseconds below, so return the zero time.Time instead.
+	if t == [8]byte{} {
+		return time.Time{}
+	}
 	// Taken from https://golang.org/src/syscall/types_windows.go#L352, which is only available on Windows
 	nsec := int64(binary.LittleEndian.Uint32(t[4:]))<<32 + int64(binary.LittleEndian.Uint32(t[:4]))
 	// change starting time to the Epoch (00:00:00 UTC, January 1, 1970)
@@ -92,6 +155,35 @@ func toTime(t [8]byte) time.Time {
 	return time.Unix(0, nsec)
 }
 
+// fromTime is the reverse of toTime and converts a time.Time to an 8-byte
+// Windows Filetime. The zero time.Time becomes a zero Filetime.
+func fromTime(t time.Time) (ft [8]byte) {
+	if t.IsZero() {
+		return ft
+	}
+	nsec := t.UnixNano()/100 + 116444736000000000
+	binary.LittleEndian.PutUint64(ft[:], uint64(nsec))
+	return ft
+}
+
+// dosTime converts a 4-byte MS-DOS date and time (used in shell items) to
+// time.Time. The date is the first uint16 and the time is the second. MS-DOS
+// times are local to the machine that created them and are returned as UTC.
+// Seconds have a resolution of two seconds.
+func dosTime(b []byte) time.Time {
+	if len(b) < 4 {
+		return time.Time{}
+	}
+	date := binary.LittleEndian.Uint16(b[0:2])
+	tm := binary.LittleEndian.Uint16(b[2:4])
+	if date == 0 && tm == 0 {
+		return time.Time{}
+	}
+	return time.Date(
+		int(date>>9)+1980, time.Month(date>>5&0x0F), int(date&0x1F),
+		int(tm>>11), int(tm>>5&0x3F), int(tm&0x1F)*2, 0, time.UTC)
+}
+
 // formatTime converts a 8-byte Windows Filetime to time.Time and then formats
 // it to string.
 func formatTime(t [8]byte) string {
@@ -125,3 +217,15 @@ func matchFlag(flag uint32, flagText []string) FlagMap {
 	}
 	return mp
 }
+
+// flagValue is the reverse of matchFlag. It sets the bit for every flag in
+// flagText that is true in the FlagMap and returns the resulting uint32.
+func flagValue(fm FlagMap, flagText []string) uint32 {
+	var flag uint32
+	for bitIndex, text := range flagText {
+		if fm[text] {
+			flag |= 1 << uint(bitIndex)
+		}
+	}
+	return flag
+}
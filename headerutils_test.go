@@ -18,7 +18,9 @@ func TestHotKey(t *testing.T) {
 		{"invalid-low", args{uint16(0x0101)}, "No Key Assigned"},
 		{"invalid-low", args{uint16(0x0001)}, "No Key Assigned"},
 		{"invalid-high", args{uint16(0x0035)}, "No Key Assigned"},
-		{"invalid-high", args{uint16(0x0535)}, "No Key Assigned"},
+		{"shift+alt+5", args{uint16(0x0535)}, "SHIFT+ALT+5"},
+		{"ctrl+alt+A", args{uint16(0x0641)}, "CTRL+ALT+A"},
+		{"invalid-high-ext", args{uint16(0x0841)}, "No Key Assigned"},
 		{"alt+F12", args{uint16(0x047B)}, "ALT+F12"},
 		{"ctrl+F12", args{uint16(0x027B)}, "CTRL+F12"},
 		{"invalid-low-between", args{uint16(0x025B)}, "No Key Assigned"},
@@ -1,9 +1,13 @@
 package lnk
 
 import (
+	"bytes"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
 )
 
 // ItemList structure.
@@ -18,6 +22,9 @@ type LinkTargetIDListSection struct {
 	// // Section's raw bytes.
 	List IDList
 	// Raw conta
+
+	// Span is the location of the section.
+	Span Span
 }
 
 // IDList represents a persisted item ID list.
@@ -38,17 +45,27 @@ type ItemID struct {
 	Size uint16
 	// Data length is size-2 bytes.
 	Data []byte
+	// Item is the typed shell item decoded from Data. It's UnknownItem if the
+	// class type is not supported. Not used when writing.
+	Item ShellItem
+	// Span is the location of the ItemID including the size.
+	Span Span
 }
 
 // LinkTarget returns a populated LinkTarget based on bytes passed. []byte
 // should point to the start of the section. Normally this will be offset 0x4c
-// of the lnk file.
+// of the lnk file. Errors are *ParseError. DefaultLimits are used.
 func LinkTarget(r io.Reader) (li LinkTargetIDListSection, err error) {
+	return linkTarget(r, newBudget(DefaultLimits))
+}
+
+// linkTarget parses the LinkTargetIDList within the limits in b.
+func linkTarget(r io.Reader, b *budget) (li LinkTargetIDListSection, err error) {
 
 	// Read the first two bytes to get the IDListSize.
 	err = binary.Read(r, binary.LittleEndian, &li.IDListSize)
 	if err != nil {
-		return li, fmt.Errorf("lnk.LinkTarget: read IDListSize - %s", err.Error())
+		return li, parseError("LinkTargetIDList", "IDListSize", 0, err)
 	}
 	// fmt.Println(li.IDListSize)
 
@@ -62,10 +79,14 @@ func LinkTarget(r io.Reader) (li LinkTargetIDListSection, err error) {
 	// Start populating ItemIDs.
 	var items []ItemID
 	var itemSize uint16
+	// Offset of the current ItemID.
+	offset := int64(2)
 	for {
 		err = binary.Read(r, binary.LittleEndian, &itemSize)
 		if err != nil {
-			return li, fmt.Errorf("lnk.LinkTarget: read item size - %s", err.Error())
+			// Keep the items before the error for lenient parsing.
+			li.List.ItemIDList = items
+			return li, parseError("LinkTargetIDList", "ItemIDSize", offset, err)
 		}
 		// Check if we have reach the TerminalID
 		if itemSize == 0 {
@@ -73,13 +94,30 @@ func LinkTarget(r io.Reader) (li LinkTargetIDListSection, err error) {
 			// fmt.Println("Reached TerminalID")
 			break
 		}
+		if itemSize < 2 {
+			li.List.ItemIDList = items
+			return li, parseError("LinkTargetIDList", "ItemIDSize", offset,
+				fmt.Errorf("%w - got %d, want at least 2", ErrBadSize, itemSize))
+		}
+		if err = b.item(uint64(itemSize - 2)); err != nil {
+			li.List.ItemIDList = items
+			return li, parseError("LinkTargetIDList", "ItemIDSize", offset, err)
+		}
 		// If not, read those many bytes-2.
 		itemData := make([]byte, itemSize-2)
 		err = binary.Read(r, binary.LittleEndian, &itemData)
 		if err != nil {
-			return li, fmt.Errorf("lnk.LinkTarget: read item data - %s", err.Error())
+			li.List.ItemIDList = items
+			return li, parseError("LinkTargetIDList", "ItemID.Data", offset+2, err)
+		}
+		dec := &itemDecoder{cp: CodePageAuto}
+		item := parseShellItem(itemData, dec)
+		if dec.err != nil {
+			b.warn("LinkTargetIDList", fmt.Sprintf("ItemID[%d]", len(items)), offset, dec.err)
 		}
-		items = append(items, ItemID{Size: itemSize, Data: itemData})
+		items = append(items, ItemID{Size: itemSize, Data: itemData, Item: item,
+			Span: Span{Offset: offset, Size: int64(itemSize)}})
+		offset += int64(itemSize)
 	}
 
 	// fmt.Println(len(items))
@@ -93,6 +131,119 @@ func LinkTarget(r io.Reader) (li LinkTargetIDListSection, err error) {
 	idList.ItemIDList = items
 
 	li.List = idList
+	// offset is at the TerminalID.
+	li.Span = Span{Size: offset + 2}
 
 	return li, err
 }
+
+// shift adds base to the spans of the section and its ItemIDs.
+func (li *LinkTargetIDListSection) shift(base int64) {
+	li.Span.shift(base)
+	for i := range li.List.ItemIDList {
+		li.List.ItemIDList[i].Span.shift(base)
+	}
+}
+
+// MarshalBinary returns the LinkTargetIDList as it appears on disk.
+// IDListSize and the size of each ItemID are calculated from the data.
+func (li LinkTargetIDListSection) MarshalBinary() ([]byte, error) {
+	var items bytes.Buffer
+	for _, it := range li.List.ItemIDList {
+		if len(it.Data)+2 > 0xFFFF {
+			return nil, fmt.Errorf("lnk.LinkTargetIDListSection.MarshalBinary: ItemID too large - got %d bytes", len(it.Data)+2)
+		}
+		items.Write(uint16Byte(uint16(len(it.Data) + 2)))
+		items.Write(it.Data)
+	}
+	// TerminalID.
+	items.Write(uint16Byte(0))
+
+	if items.Len() > 0xFFFF {
+		return nil, fmt.Errorf("lnk.LinkTargetIDListSection.MarshalBinary: IDList too large - got %d bytes", items.Len())
+	}
+	return append(uint16Byte(uint16(items.Len())), items.Bytes()...), nil
+}
+
+// Path returns the location of the target built from the names of the shell
+// items, e.g. My Computer\C:\Users\x\file.txt. Network shares and URIs
+// replace the items before them, e.g. \\server\share\dir. Items without a
+// name are skipped.
+func (li LinkTargetIDListSection) Path() string {
+	return itemsPath(li.List.ItemIDList)
+}
+
+// PathFrom returns the Path of the items starting from the item at offset.
+// offset is from the start of the IDList (after IDListSize) and is used by
+// the KnownFolder and SpecialFolder blocks. Returns false if no item starts
+// at offset.
+func (li LinkTargetIDListSection) PathFrom(offset uint32) (string, bool) {
+	i := li.ItemAt(offset)
+	if i < 0 {
+		return "", false
+	}
+	return itemsPath(li.List.ItemIDList[i:]), true
+}
+
+// ItemAt returns the index of the ItemID at offset from the start of the
+// IDList, -1 if no item starts at offset.
+func (li LinkTargetIDListSection) ItemAt(offset uint32) int {
+	var current uint32
+	for i, it := range li.List.ItemIDList {
+		if current == offset {
+			return i
+		}
+		current += uint32(it.Size)
+	}
+	return -1
+}
+
+// itemsPath joins the names of the shell items. See Path.
+func itemsPath(items []ItemID) string {
+	var parts []string
+	for _, it := range items {
+		if it.Item == nil {
+			continue
+		}
+		name := it.Item.Name()
+		if name == "" {
+			continue
+		}
+		switch it.Item.(type) {
+		case NetworkItem:
+			// Only UNC paths are complete. Other network items (e.g. domain
+			// names) are part of the path.
+			if strings.HasPrefix(name, `\\`) {
+				parts = nil
+			}
+		case URIItem:
+			parts = nil
+		}
+		parts = append(parts, strings.TrimRight(name, `\`))
+	}
+	path := strings.Join(parts, `\`)
+	// Add the separator back to lone drive letters, e.g. My Computer\C:\.
+	if strings.HasSuffix(path, ":") {
+		path += `\`
+	}
+	return path
+}
+
+// String prints the shell items of the LinkTargetIDList in a table.
+func (li LinkTargetIDListSection) String() string {
+	var sb strings.Builder
+
+	table := tablewriter.NewWriter(&sb)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+	table.SetRowLine(true)
+
+	table.SetHeader([]string{"LinkTargetIDList", "Value"})
+
+	table.Append([]string{"IDListSize", uint16Str(li.IDListSize)})
+	for i, it := range li.List.ItemIDList {
+		table.Append([]string{fmt.Sprintf("Item %d", i), shellItemStr(it.Item)})
+	}
+
+	table.Render()
+	return sb.String()
+}
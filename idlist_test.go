@@ -0,0 +1,71 @@
+package lnk
+
+import "testing"
+
+func TestPath(t *testing.T) {
+	myComputer, _ := parseGUID("{20D04FE0-3AEA-1069-A2D8-08002B30309D}")
+	items := func(items ...ShellItem) LinkTargetIDListSection {
+		var li LinkTargetIDListSection
+		for _, it := range items {
+			li.List.ItemIDList = append(li.List.ItemIDList, ItemID{Item: it})
+		}
+		return li
+	}
+	tests := []struct {
+		name string
+		li   LinkTargetIDListSection
+		want string
+	}{
+		{"drive", items(
+			RootFolderItem{Class: 0x1F, FolderID: myComputer},
+			VolumeItem{Class: 0x2F, Drive: `C:\`},
+			FileEntryItem{Class: 0x31, PrimaryName: "Users"},
+			FileEntryItem{Class: 0x32, PrimaryName: "FILE~1.TXT",
+				Extension: &FileEntryExtension{LongName: "file.txt"}},
+		), `My Computer\C:\Users\file.txt`},
+		{"drive-only", items(
+			RootFolderItem{Class: 0x1F, FolderID: myComputer},
+			VolumeItem{Class: 0x2F, Drive: `C:\`},
+		), `My Computer\C:\`},
+		{"unc", items(
+			NetworkItem{Class: 0x47, Location: "Entire Network"},
+			NetworkItem{Class: 0x42, Location: `\\server`},
+			NetworkItem{Class: 0xC3, Location: `\\server\share`},
+			FileEntryItem{Class: 0x31, PrimaryName: "dir"},
+		), `\\server\share\dir`},
+		{"unknown", items(
+			UnknownItem{Class: 0x99},
+			FileEntryItem{Class: 0x31, PrimaryName: "dir"},
+		), `dir`},
+		{"empty", items(), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.li.Path(); got != tt.want {
+				t.Errorf("Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathSamples(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"test/nem.test", `My Computer\Desktop\2016-01-28-083758-HasherResults.xlsx`},
+		{"test/test.lnk", `Users Files\AppData\Local\Programs\Microsoft VS Code\Code.exe`},
+		{"test/Windows Store.lnk", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			f, err := File(tt.filename)
+			if err != nil {
+				t.Fatalf("File() error = %v", err)
+			}
+			if got := f.IDList.Path(); got != tt.want {
+				t.Errorf("Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package lnk
+
+// folder is a shell folder, known folder or CSIDL.
+type folder struct {
+	// id is the constant, e.g. FOLDERID_Documents or CSIDL_PERSONAL. Empty
+	// for shell folder CLSIDs.
+	id string
+	// name is the display name used in paths, e.g. Documents.
+	name string
+}
+
+// knownFolders maps shell folder CLSIDs and known folder IDs (KNOWNFOLDERID)
+// to their constants and display names. Used to name virtual folders in
+// paths and the KnownFolderDataBlock.
+// https://docs.microsoft.com/en-us/windows/win32/shell/knownfolderid
+var knownFolders = map[string]folder{
+	// Shell folders in root folder and control panel shell items.
+	"{20D04FE0-3AEA-1069-A2D8-08002B30309D}": {"", "My Computer"},
+	"{208D2C60-3AEA-1069-A2D7-08002B30309D}": {"", "My Network Places"},
+	"{450D8FBA-AD25-11D0-98A8-0800361B1103}": {"", "My Documents"},
+	"{59031A47-3F72-44A7-89C5-5595FE6B30EE}": {"", "Users Files"},
+	"{645FF040-5081-101B-9F08-00AA002F954E}": {"", "Recycle Bin"},
+	"{21EC2020-3AEA-1069-A2DD-08002B30309D}": {"", "Control Panel"},
+	"{26EE0668-A00A-44D7-9371-BEB064C98683}": {"", "Control Panel"},
+	"{871C5380-42A0-1069-A2EA-08002B30309D}": {"", "Internet Explorer"},
+	"{031E4825-7B94-4DC3-B131-E946B44C8DD5}": {"", "Libraries"},
+	"{F02C1A0D-BE21-4350-88B0-7367FC96EF3C}": {"", "Network"},
+	"{679F85CB-0220-4080-B29B-5540CC05AAB6}": {"", "Quick Access"},
+	"{018D5C66-4533-4307-9B53-224DE2ED1FE6}": {"", "OneDrive"},
+	"{4234D49B-0245-4DF3-B780-3893943456E1}": {"", "Applications"},
+	"{2227A280-3AEA-1069-A2DE-08002B30309D}": {"", "Printers"},
+	"{D20EA4E1-3957-11D2-A40B-0C5020524153}": {"", "Administrative Tools"},
+	"{D3162B92-9365-467A-956B-92703ACA08AF}": {"", "Documents"},
+	"{088E3905-0323-4B02-9826-5D99428E115F}": {"", "Downloads"},
+	"{3DFDF296-DBEC-4FB4-81D1-6A3438BCF4DE}": {"", "Music"},
+	"{24AD3AD4-A569-4530-98E1-AB02F9417AA8}": {"", "Pictures"},
+	"{F86FA3AB-70D2-4FC7-9C99-FCBF05467F3A}": {"", "Videos"},
+	"{0DB7E03F-FC29-4DC6-9020-FF41B59E513A}": {"", "3D Objects"},
+
+	// Known folders.
+	"{008CA0B1-55B4-4C56-B8A8-4DE4B299D3BE}": {"FOLDERID_AccountPictures", "Account Pictures"},
+	"{DE61D971-5EBC-4F02-A3A9-6C82895E5C04}": {"FOLDERID_AddNewPrograms", "Get Programs"},
+	"{724EF170-A42D-4FEF-9F26-B60E846FBA4F}": {"FOLDERID_AdminTools", "Administrative Tools"},
+	"{B2C5E279-7ADD-439F-B28C-C41FE1BBF672}": {"FOLDERID_AppDataDesktop", "AppData Desktop"},
+	"{7BE16610-1F7F-44AC-BFF0-83E15F2FFCA1}": {"FOLDERID_AppDataDocuments", "AppData Documents"},
+	"{7CFBEFBC-DE1F-45AA-B843-A542AC536CC9}": {"FOLDERID_AppDataFavorites", "AppData Favorites"},
+	"{559D40A3-A036-40FA-AF61-84CB430A4D34}": {"FOLDERID_AppDataProgramData", "AppData ProgramData"},
+	"{A305CE99-F527-492B-8B1A-7E76FA98D6E4}": {"FOLDERID_AppUpdates", "Installed Updates"},
+	"{A3918781-E5F2-4890-B3D9-A7E54332328C}": {"FOLDERID_ApplicationShortcuts", "Application Shortcuts"},
+	"{1E87508D-89C2-42F0-8A7E-645A0F50CA58}": {"FOLDERID_AppsFolder", "Applications"},
+	"{9E52AB10-F80D-49DF-ACB8-4330F5687855}": {"FOLDERID_CDBurning", "CD Burning"},
+	"{AB5FB87B-7CE2-4F83-915D-550846C9537B}": {"FOLDERID_CameraRoll", "Camera Roll"},
+	"{DF7266AC-9274-4867-8D55-3BD661DE872D}": {"FOLDERID_ChangeRemovePrograms", "Programs and Features"},
+	"{D0384E7D-BAC3-4797-8F14-CBA229B392B5}": {"FOLDERID_CommonAdminTools", "Common Administrative Tools"},
+	"{C1BAE2D0-10DF-4334-BEDD-7AA20B227A9D}": {"FOLDERID_CommonOEMLinks", "OEM Links"},
+	"{0139D44E-6AFE-49F2-8690-3DAFCAE6FFB8}": {"FOLDERID_CommonPrograms", "Common Programs"},
+	"{A4115719-D62E-491D-AA7C-E74B8BE3B067}": {"FOLDERID_CommonStartMenu", "Common Start Menu"},
+	"{82A5EA35-D9CD-47C5-9629-E15D2F714E6E}": {"FOLDERID_CommonStartup", "Common Startup"},
+	"{B94237E7-57AC-4347-9151-B08C6C32D1F7}": {"FOLDERID_CommonTemplates", "Common Templates"},
+	"{0AC0837C-BBF8-452A-850D-79D08E667CA7}": {"FOLDERID_ComputerFolder", "Computer"},
+	"{4BFEFB45-347D-4006-A5BE-AC0CB0567192}": {"FOLDERID_ConflictFolder", "Conflicts"},
+	"{6F0CD92B-2E97-45D1-88FF-B0D186B8DEDD}": {"FOLDERID_ConnectionsFolder", "Network Connections"},
+	"{56784854-C6CB-462B-8169-88E350ACB882}": {"FOLDERID_Contacts", "Contacts"},
+	"{82A74AEB-AEB4-465C-A014-D097EE346D63}": {"FOLDERID_ControlPanelFolder", "Control Panel"},
+	"{2B0F765D-C0E9-4171-908E-08A611B84FF6}": {"FOLDERID_Cookies", "Cookies"},
+	"{B4BFCC3A-DB2C-424C-B029-7FE99A87C641}": {"FOLDERID_Desktop", "Desktop"},
+	"{5CE4A5E9-E4EB-479D-B89F-130C02886155}": {"FOLDERID_DeviceMetadataStore", "Device Metadata Store"},
+	"{FDD39AD0-238F-46AF-ADB4-6C85480369C7}": {"FOLDERID_Documents", "Documents"},
+	"{7B0DB17D-9CD2-4A93-9733-46CC89022E7C}": {"FOLDERID_DocumentsLibrary", "Documents Library"},
+	"{374DE290-123F-4565-9164-39C4925E467B}": {"FOLDERID_Downloads", "Downloads"},
+	"{1777F761-68AD-4D8A-87BD-30B759FA33DD}": {"FOLDERID_Favorites", "Favorites"},
+	"{FD228CB7-AE11-4AE3-864C-16F3910AB8FE}": {"FOLDERID_Fonts", "Fonts"},
+	"{054FAE61-4DD8-4787-80B6-090220C4B700}": {"FOLDERID_GameTasks", "Game Tasks"},
+	"{CAC52C1A-B53D-4EDC-92D7-6B2E8AC19434}": {"FOLDERID_Games", "Games"},
+	"{D9DC8A3B-B784-432E-A781-5A1130A75963}": {"FOLDERID_History", "History"},
+	"{52528A6B-B9E3-4ADD-B60D-588C2DBA842D}": {"FOLDERID_HomeGroup", "Homegroup"},
+	"{BCB5256F-79F6-4CEE-B725-DC34E402FD46}": {"FOLDERID_ImplicitAppShortcuts", "Implicit Application Shortcuts"},
+	"{352481E8-33BE-4251-BA85-6007CAEDCF9D}": {"FOLDERID_InternetCache", "Temporary Internet Files"},
+	"{4D9F7874-4E0C-4904-967B-40B0D20C3E4B}": {"FOLDERID_InternetFolder", "The Internet"},
+	"{1B3EA5DC-B587-4786-B4EF-BD1DC332AEAE}": {"FOLDERID_Libraries", "Libraries"},
+	"{BFB9D5E0-C6A9-404C-B2B2-AE6DB6AF4968}": {"FOLDERID_Links", "Links"},
+	"{F1B32785-6FBA-4FCF-9D55-7B8E7F157091}": {"FOLDERID_LocalAppData", "Local AppData"},
+	"{A520A1A4-1780-4FF6-BD18-167343C5AF16}": {"FOLDERID_LocalAppDataLow", "LocalLow AppData"},
+	"{2A00375E-224C-49DE-B8D1-440DF7EF3DDC}": {"FOLDERID_LocalizedResourcesDir", "Localized Resources"},
+	"{4BD8D571-6D19-48D3-BE97-422220080E43}": {"FOLDERID_Music", "Music"},
+	"{2112AB0A-C86A-4FFE-A368-0DE96E47012E}": {"FOLDERID_MusicLibrary", "Music Library"},
+	"{C5ABBF53-E17F-4121-8900-86626FC2C973}": {"FOLDERID_NetHood", "Network Shortcuts"},
+	"{D20BEEC4-5CA8-4905-AE3B-BF251EA09B53}": {"FOLDERID_NetworkFolder", "Network"},
+	"{31C0DD25-9439-4F12-BF41-7FF4EDA38722}": {"FOLDERID_Objects3D", "3D Objects"},
+	"{2C36C0AA-5812-4B87-BFD0-4CD0DFB19B39}": {"FOLDERID_OriginalImages", "Original Images"},
+	"{69D2CF90-FC33-4FB7-9A0C-EBB0F0FCB43C}": {"FOLDERID_PhotoAlbums", "Slide Shows"},
+	"{33E28130-4E1E-4676-835A-98395C3BC3BB}": {"FOLDERID_Pictures", "Pictures"},
+	"{A990AE9F-A03B-4E80-94BC-9912D7504104}": {"FOLDERID_PicturesLibrary", "Pictures Library"},
+	"{DE92C1C7-837F-4F69-A3BB-86E631204A23}": {"FOLDERID_Playlists", "Playlists"},
+	"{9274BD8D-CFD1-41C3-B35E-B13F55A758F4}": {"FOLDERID_PrintHood", "Printer Shortcuts"},
+	"{76FC4E2D-D6AD-4519-A663-37BD56068185}": {"FOLDERID_PrintersFolder", "Printers"},
+	"{5E6C858F-0E22-4760-9AFE-EA3317B67173}": {"FOLDERID_Profile", "Profile"},
+	"{62AB5D82-FDC1-4DC3-A9DD-070D1D495D97}": {"FOLDERID_ProgramData", "ProgramData"},
+	"{905E63B6-C1BF-494E-B29C-65B732D3D21A}": {"FOLDERID_ProgramFiles", "Program Files"},
+	"{F7F1ED05-9F6D-47A2-AAAE-29D317C6F066}": {"FOLDERID_ProgramFilesCommon", "Common Files"},
+	"{6365D5A7-0F0D-45E5-87F6-0DA56B6A4F7D}": {"FOLDERID_ProgramFilesCommonX64", "Common Files (x64)"},
+	"{DE974D24-D9C6-4D3E-BF91-F4455120B917}": {"FOLDERID_ProgramFilesCommonX86", "Common Files (x86)"},
+	"{6D809377-6AF0-444B-8957-A3773F02200E}": {"FOLDERID_ProgramFilesX64", "Program Files (x64)"},
+	"{7C5A40EF-A0FB-4BFC-874A-C0F2E0B9FA8E}": {"FOLDERID_ProgramFilesX86", "Program Files (x86)"},
+	"{A77F5D77-2E2B-44C3-A6A2-ABA601054A51}": {"FOLDERID_Programs", "Programs"},
+	"{DFDF76A2-C82A-4D63-906A-5644AC457385}": {"FOLDERID_Public", "Public"},
+	"{C4AA340D-F20F-4863-AFEF-F87EF2E6BA25}": {"FOLDERID_PublicDesktop", "Public Desktop"},
+	"{ED4824AF-DCE4-45A8-81E2-FC7965083634}": {"FOLDERID_PublicDocuments", "Public Documents"},
+	"{3D644C9B-1FB8-4F30-9B45-F670235F79C0}": {"FOLDERID_PublicDownloads", "Public Downloads"},
+	"{DEBF2536-E1A8-4C59-B6A2-414586476AEA}": {"FOLDERID_PublicGameTasks", "Public Game Tasks"},
+	"{48DAF80B-E6CF-4F4E-B800-0E69D84EE384}": {"FOLDERID_PublicLibraries", "Public Libraries"},
+	"{3214FAB5-9757-4298-BB61-92A9DEAA44FF}": {"FOLDERID_PublicMusic", "Public Music"},
+	"{B6EBFB86-6907-413C-9AF7-4FC2ABF07CC5}": {"FOLDERID_PublicPictures", "Public Pictures"},
+	"{E555AB60-153B-4D17-9F04-A5FE99FC15EC}": {"FOLDERID_PublicRingtones", "Public Ringtones"},
+	"{0482AF6C-08F1-4C34-8C90-E17EC98B1E17}": {"FOLDERID_PublicUserTiles", "Public Account Pictures"},
+	"{2400183A-6185-49FB-A2D8-4A392A602BA3}": {"FOLDERID_PublicVideos", "Public Videos"},
+	"{52A4F021-7B75-48A9-9F6B-4B87A210BC8F}": {"FOLDERID_QuickLaunch", "Quick Launch"},
+	"{AE50C081-EBD2-438A-8655-8A092E34987A}": {"FOLDERID_Recent", "Recent Items"},
+	"{1A6FDBA2-F42D-4358-A798-B74D745926C5}": {"FOLDERID_RecordedTVLibrary", "Recorded TV"},
+	"{B7534046-3ECB-4C18-BE4E-64CD4CB7D6AC}": {"FOLDERID_RecycleBinFolder", "Recycle Bin"},
+	"{8AD10C31-2ADB-4296-A8F7-E4701232C972}": {"FOLDERID_ResourceDir", "Resources"},
+	"{C870044B-F49E-4126-A9C3-B52A1FF411E8}": {"FOLDERID_Ringtones", "Ringtones"},
+	"{3EB685DB-65F9-4CF6-A03A-E3EF65729F3D}": {"FOLDERID_RoamingAppData", "AppData"},
+	"{00BCFC5A-ED94-4E48-96A1-3F6217F21990}": {"FOLDERID_RoamingTiles", "Roaming Tiles"},
+	"{B250C668-F57D-4EE1-A63C-290EE7D1AA1F}": {"FOLDERID_SampleMusic", "Sample Music"},
+	"{C4900540-2379-4C75-844B-64E6FAF8716B}": {"FOLDERID_SamplePictures", "Sample Pictures"},
+	"{15CA69B3-30EE-49C1-ACE1-6B5EC372AFB5}": {"FOLDERID_SamplePlaylists", "Sample Playlists"},
+	"{859EAD94-2E85-48AD-A71A-0969CB56A6CD}": {"FOLDERID_SampleVideos", "Sample Videos"},
+	"{4C5C32FF-BB9D-43B0-B5B4-2D72E54EAAA4}": {"FOLDERID_SavedGames", "Saved Games"},
+	"{3B193882-D3AD-4EAB-965A-69829D1FB59F}": {"FOLDERID_SavedPictures", "Saved Pictures"},
+	"{7D1D3A04-DEBB-4115-95CF-2F29DA2920DA}": {"FOLDERID_SavedSearches", "Searches"},
+	"{B7BEDE81-DF94-4682-A7D8-57A52620B86F}": {"FOLDERID_Screenshots", "Screenshots"},
+	"{0D4C3DB6-03A3-462F-A0E6-08924C41B5D4}": {"FOLDERID_SearchHistory", "Search History"},
+	"{190337D1-B8CA-4121-A639-6D472D16972A}": {"FOLDERID_SearchHome", "Search Results"},
+	"{7E636BFE-DFA9-4D5E-B456-D7B39851D8A9}": {"FOLDERID_SearchTemplates", "Search Templates"},
+	"{8983036C-27C0-404B-8F08-102D10DCFD74}": {"FOLDERID_SendTo", "SendTo"},
+	"{7B396E54-9EC5-4300-BE0A-2482EBAE1A26}": {"FOLDERID_SidebarDefaultParts", "Default Gadgets"},
+	"{A75D362E-50FC-4FB7-AC2C-A8BEAA314493}": {"FOLDERID_SidebarParts", "Gadgets"},
+	"{A52BBA46-E9E1-435F-B3D9-28DAA648C0F6}": {"FOLDERID_SkyDrive", "OneDrive"},
+	"{767E6811-49CB-4273-87C2-20F355E1085B}": {"FOLDERID_SkyDriveCameraRoll", "OneDrive Camera Roll"},
+	"{24D89E24-2F19-4534-9DDE-6A6671FBB8FE}": {"FOLDERID_SkyDriveDocuments", "OneDrive Documents"},
+	"{339719B5-8C47-4894-94C2-D8F77ADD44A6}": {"FOLDERID_SkyDrivePictures", "OneDrive Pictures"},
+	"{625B53C3-AB48-4EC1-BA1F-A1EF4146FC19}": {"FOLDERID_StartMenu", "Start Menu"},
+	"{B97D20BB-F46A-4C97-BA10-5E3608430854}": {"FOLDERID_Startup", "Startup"},
+	"{43668BF8-C14E-49B2-97C9-747784D784B7}": {"FOLDERID_SyncManagerFolder", "Sync Center"},
+	"{289A9A43-BE44-4057-A41B-587A76D7E7F9}": {"FOLDERID_SyncResultsFolder", "Sync Results"},
+	"{0F214138-B1D3-4A90-BBA9-27CBC0C5389A}": {"FOLDERID_SyncSetupFolder", "Sync Setup"},
+	"{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}": {"FOLDERID_System", "System"},
+	"{D65231B0-B2F1-4857-A4CE-A8E7C6EA7D27}": {"FOLDERID_SystemX86", "System (x86)"},
+	"{A63293E8-664E-48DB-A079-DF759E0509F7}": {"FOLDERID_Templates", "Templates"},
+	"{9E3995AB-1F9C-4F13-B827-48B24B6C7174}": {"FOLDERID_UserPinned", "User Pinned"},
+	"{0762D272-C50A-4BB0-A382-697DCD729B80}": {"FOLDERID_UserProfiles", "Users"},
+	"{5CD7AEE2-2219-4A67-B85D-6C9CE15660CB}": {"FOLDERID_UserProgramFiles", "User Programs"},
+	"{BCBD3057-CA5C-4622-B42D-BC56DB0AE516}": {"FOLDERID_UserProgramFilesCommon", "User Common Programs"},
+	"{F3CE0F7C-4901-4ACC-8648-D5D44B04EF8F}": {"FOLDERID_UsersFiles", "Users Files"},
+	"{A302545D-DEFF-464B-ABE8-61C8648D939B}": {"FOLDERID_UsersLibraries", "Users Libraries"},
+	"{18989B1D-99B5-455B-841C-AB7C74E4DDFC}": {"FOLDERID_Videos", "Videos"},
+	"{491E922F-5643-4AF4-A7EB-4E7A138D8174}": {"FOLDERID_VideosLibrary", "Videos Library"},
+	"{F38BF404-1D43-42F2-9305-67DE0B28FC23}": {"FOLDERID_Windows", "Windows"},
+}
+
+// folderName returns the display name of a shell folder CLSID or known folder
+// ID. Unknown GUIDs are returned in registry format.
+func folderName(g GUID) string {
+	if f, ok := knownFolders[g.String()]; ok {
+		return f.name
+	}
+	return g.String()
+}
+
+// specialFolders maps CSIDL values in SpecialFolderDataBlock to their
+// constants and the names of the folders.
+// https://docs.microsoft.com/en-us/windows/win32/shell/csidl
+var specialFolders = map[uint32]folder{
+	0x00: {"CSIDL_DESKTOP", "Desktop"},
+	0x01: {"CSIDL_INTERNET", "The Internet"},
+	0x02: {"CSIDL_PROGRAMS", "Programs"},
+	0x03: {"CSIDL_CONTROLS", "Control Panel"},
+	0x04: {"CSIDL_PRINTERS", "Printers"},
+	0x05: {"CSIDL_PERSONAL", "My Documents"},
+	0x06: {"CSIDL_FAVORITES", "Favorites"},
+	0x07: {"CSIDL_STARTUP", "Startup"},
+	0x08: {"CSIDL_RECENT", "Recent"},
+	0x09: {"CSIDL_SENDTO", "SendTo"},
+	0x0A: {"CSIDL_BITBUCKET", "Recycle Bin"},
+	0x0B: {"CSIDL_STARTMENU", "Start Menu"},
+	0x0C: {"CSIDL_MYDOCUMENTS", "My Documents"},
+	0x0D: {"CSIDL_MYMUSIC", "My Music"},
+	0x0E: {"CSIDL_MYVIDEO", "My Videos"},
+	0x10: {"CSIDL_DESKTOPDIRECTORY", "Desktop"},
+	0x11: {"CSIDL_DRIVES", "My Computer"},
+	0x12: {"CSIDL_NETWORK", "Network"},
+	0x13: {"CSIDL_NETHOOD", "NetHood"},
+	0x14: {"CSIDL_FONTS", "Fonts"},
+	0x15: {"CSIDL_TEMPLATES", "Templates"},
+	0x16: {"CSIDL_COMMON_STARTMENU", "Common Start Menu"},
+	0x17: {"CSIDL_COMMON_PROGRAMS", "Common Programs"},
+	0x18: {"CSIDL_COMMON_STARTUP", "Common Startup"},
+	0x19: {"CSIDL_COMMON_DESKTOPDIRECTORY", "Common Desktop"},
+	0x1A: {"CSIDL_APPDATA", "AppData"},
+	0x1B: {"CSIDL_PRINTHOOD", "PrintHood"},
+	0x1C: {"CSIDL_LOCAL_APPDATA", "Local AppData"},
+	0x1D: {"CSIDL_ALTSTARTUP", "Alternate Startup"},
+	0x1E: {"CSIDL_COMMON_ALTSTARTUP", "Common Alternate Startup"},
+	0x1F: {"CSIDL_COMMON_FAVORITES", "Common Favorites"},
+	0x20: {"CSIDL_INTERNET_CACHE", "Temporary Internet Files"},
+	0x21: {"CSIDL_COOKIES", "Cookies"},
+	0x22: {"CSIDL_HISTORY", "History"},
+	0x23: {"CSIDL_COMMON_APPDATA", "ProgramData"},
+	0x24: {"CSIDL_WINDOWS", "Windows"},
+	0x25: {"CSIDL_SYSTEM", "System"},
+	0x26: {"CSIDL_PROGRAM_FILES", "Program Files"},
+	0x27: {"CSIDL_MYPICTURES", "My Pictures"},
+	0x28: {"CSIDL_PROFILE", "Profile"},
+	0x29: {"CSIDL_SYSTEMX86", "System (x86)"},
+	0x2A: {"CSIDL_PROGRAM_FILESX86", "Program Files (x86)"},
+	0x2B: {"CSIDL_PROGRAM_FILES_COMMON", "Common Files"},
+	0x2C: {"CSIDL_PROGRAM_FILES_COMMONX86", "Common Files (x86)"},
+	0x2D: {"CSIDL_COMMON_TEMPLATES", "Common Templates"},
+	0x2E: {"CSIDL_COMMON_DOCUMENTS", "Common Documents"},
+	0x2F: {"CSIDL_COMMON_ADMINTOOLS", "Common Administrative Tools"},
+	0x30: {"CSIDL_ADMINTOOLS", "Administrative Tools"},
+	0x31: {"CSIDL_CONNECTIONS", "Network Connections"},
+	0x35: {"CSIDL_COMMON_MUSIC", "Common Music"},
+	0x36: {"CSIDL_COMMON_PICTURES", "Common Pictures"},
+	0x37: {"CSIDL_COMMON_VIDEO", "Common Videos"},
+	0x38: {"CSIDL_RESOURCES", "Resources"},
+	0x39: {"CSIDL_RESOURCES_LOCALIZED", "Localized Resources"},
+	0x3A: {"CSIDL_COMMON_OEM_LINKS", "OEM Links"},
+	0x3B: {"CSIDL_CDBURN_AREA", "CD Burning"},
+	0x3D: {"CSIDL_COMPUTERSNEARME", "Computers Near Me"},
+}
+
+// specialFolderName returns the name of a CSIDL or its value in hex.
+func specialFolderName(id uint32) string {
+	if f, ok := specialFolders[id]; ok {
+		return f.name
+	}
+	return uint32StrHex(id)
+}
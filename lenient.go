@@ -0,0 +1,213 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// ParseWarning is an error that was skipped in lenient mode.
+type ParseWarning struct {
+	// Section, Field and Offset are the same as ParseError. Offset is from the
+	// start of the file.
+	Section string
+	Field   string
+	Offset  int64
+	// Err is the error, it wraps the Err* sentinels when the cause is known.
+	Err error
+	// Recovery is what the parser did after the error.
+	Recovery string
+}
+
+// String returns the warning in one line.
+func (w ParseWarning) String() string {
+	return fmt.Sprintf("%s.%s at offset 0x%X - %s - %s", w.Section, w.Field, w.Offset, w.Err.Error(), w.Recovery)
+}
+
+// MarshalText returns String so warnings are readable in JSON.
+func (w ParseWarning) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
+
+// warn adds a warning for err. section is used if err is not a ParseError.
+func (f *LnkFile) warn(err error, section string, offset int64, recovery string) {
+	w := ParseWarning{Section: section, Offset: offset, Err: err, Recovery: recovery}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		w.Section, w.Field, w.Offset, w.Err = pe.Section, pe.Field, pe.Offset, pe.Err
+	}
+	f.Warnings = append(f.Warnings, w)
+}
+
+// readLenient parses an lnk file in memory. Errors after the header are
+// added to Warnings and parsing continues:
+//
+//   - A corrupt LinkTargetIDList is skipped with IDListSize and the items
+//     before the error are kept.
+//   - A corrupt LinkInfo is skipped with its size. If the size is corrupt
+//     too, the parser searches for ExtraData blocks.
+//   - A corrupt StringData keeps the strings before the error and the parser
+//     searches for ExtraData blocks.
+//   - A corrupt ExtraData block is skipped by searching for the next valid
+//     block signature. Parsing stops if a limit in b is exceeded.
+//
+// An error is only returned if the header cannot be parsed because the link
+// flags decide which sections exist.
+func readLenient(data []byte, maxSize uint64, b *budget) (f LnkFile, err error) {
+	f.Header, err = Header(bytes.NewReader(data), maxSize)
+	if errors.Is(err, ErrBadCLSID) {
+		f.warn(err, "ShellLinkHeader", 0x04, "ignored the LinkCLSID")
+		// Parse the rest of the header with a valid CLSID.
+		fixed := append([]byte(nil), data...)
+		clsID, _ := hex.DecodeString(classID)
+		copy(fixed[4:], clsID)
+		f.Header, err = Header(bytes.NewReader(fixed), maxSize)
+		copy(f.Header.LinkCLSID[:], data[4:20])
+		f.Header.Raw = data[:len(f.Header.Raw)]
+	}
+	if err != nil {
+		return f, fmt.Errorf("golnk.Read: parse Header - %w", err)
+	}
+	pos := int64(headerSize)
+
+	if f.Header.LinkFlags["HasLinkTargetIDList"] {
+		cr, n := &countingReader{r: bytes.NewReader(tail(data, pos))}, len(b.warnings)
+		f.IDList, err = linkTarget(cr, b)
+		f.IDList.shift(pos)
+		b.shiftWarnings(n, pos)
+		if err != nil {
+			f.warn(addOffset(err, pos), "LinkTargetIDList", pos, "skipped the IDList with IDListSize")
+			pos += 2 + int64(f.IDList.IDListSize)
+		} else {
+			pos += cr.n
+		}
+	}
+
+	// found is false if the end of a section is unknown.
+	found := true
+	if f.Header.LinkFlags["HasLinkInfo"] {
+		cr, n := &countingReader{r: bytes.NewReader(tail(data, pos))}, len(b.warnings)
+		f.LinkInfo, err = linkInfo(cr, maxSize, b)
+		f.LinkInfo.shift(pos)
+		b.shiftWarnings(n, pos)
+		switch {
+		case err == nil:
+			pos += cr.n
+		case f.LinkInfo.Size != 0:
+			f.warn(addOffset(err, pos), "LinkInfo", pos, "skipped the LinkInfo with LinkInfoSize")
+			pos += int64(f.LinkInfo.Size)
+		default:
+			f.warn(addOffset(err, pos), "LinkInfo", pos, "searched for ExtraData blocks")
+			found = false
+		}
+	}
+
+	if found {
+		cr, n := &countingReader{r: bytes.NewReader(tail(data, pos))}, len(b.warnings)
+		f.StringData, err = stringData(cr, f.Header.LinkFlags, b)
+		f.StringData.Span.shift(pos)
+		b.shiftWarnings(n, pos)
+		if err != nil {
+			f.warn(addOffset(err, pos), "StringData", pos, "searched for ExtraData blocks")
+			found = false
+		} else {
+			pos += cr.n
+		}
+	}
+
+	if !found {
+		if pos = nextDataBlock(data, pos); pos < 0 {
+			return f, nil
+		}
+	}
+	f.lenientBlocks(data, pos, b)
+	return f, nil
+}
+
+// lenientBlocks reads the ExtraData blocks from pos. A corrupt block is
+// skipped by searching for the next valid block.
+func (f *LnkFile) lenientBlocks(data []byte, pos int64, b *budget) {
+	start := pos
+	for {
+		n := len(b.warnings)
+		extra, err := dataBlock(bytes.NewReader(tail(data, pos)), b)
+		extra.shift(pos)
+		b.shiftWarnings(n, pos)
+		f.DataBlocks.Blocks = append(f.DataBlocks.Blocks, extra.Blocks...)
+		if err == nil {
+			f.DataBlocks.TerminalBlock = extra.TerminalBlock
+			f.DataBlocks.Span = Span{Offset: start, Size: extra.Span.End() - start}
+			return
+		}
+		err = addOffset(err, pos)
+
+		// Start of the corrupt block.
+		for _, b := range extra.Blocks {
+			pos += int64(b.Size)
+		}
+		if errors.Is(err, ErrLimitExceeded) {
+			f.warn(err, "ExtraData", pos, "stopped, limit exceeded")
+			return
+		}
+		next := nextDataBlock(data, pos+1)
+		if next < 0 {
+			f.warn(err, "ExtraData", pos, "stopped, no more blocks")
+			return
+		}
+		f.warn(err, "ExtraData", pos, fmt.Sprintf("resumed at offset 0x%X", next))
+		pos = next
+	}
+}
+
+// blockSizes are the sizes of the ExtraData blocks with a fixed size. The
+// other blocks have a minimum size.
+var blockSizes = map[uint32]uint32{
+	0xA0000001: 0x314, // EnvironmentVariableDataBlock
+	0xA0000002: 0xCC,  // ConsoleDataBlock
+	0xA0000003: 0x60,  // TrackerDataBlock
+	0xA0000004: 0x0C,  // ConsoleFEDataBlock
+	0xA0000005: 0x10,  // SpecialFolderDataBlock
+	0xA0000006: 0x314, // DarwinDataBlock
+	0xA0000007: 0x314, // IconEnvironmentDataBlock
+	0xA000000B: 0x1C,  // KnownFolderDataBlock
+}
+
+// blockMinSizes are the minimum sizes of the other ExtraData blocks.
+var blockMinSizes = map[uint32]uint32{
+	0xA0000008: 0x88, // ShimDataBlock
+	0xA0000009: 0x0C, // PropertyStoreDataBlock
+	0xA000000C: 0x0A, // VistaAndAboveIDListDataBlock
+}
+
+// nextDataBlock returns the offset of the first valid ExtraData block header
+// at or after from, -1 if there is none. A block is valid if the signature is
+// known, the size matches the block type and the block fits in data.
+func nextDataBlock(data []byte, from int64) int64 {
+	if from < 0 {
+		from = 0
+	}
+	for i := from; i+8 <= int64(len(data)); i++ {
+		size := binary.LittleEndian.Uint32(data[i:])
+		sig := binary.LittleEndian.Uint32(data[i+4:])
+		if int64(size) > int64(len(data))-i {
+			continue
+		}
+		if want, ok := blockSizes[sig]; ok && size == want {
+			return i
+		}
+		if min, ok := blockMinSizes[sig]; ok && size >= min {
+			return i
+		}
+	}
+	return -1
+}
+
+// tail returns data from pos or an empty slice if pos is after the end.
+func tail(data []byte, pos int64) []byte {
+	if pos < 0 || pos > int64(len(data)) {
+		return nil
+	}
+	return data[pos:]
+}
@@ -0,0 +1,79 @@
+package lnk
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLenient(t *testing.T) {
+	data, err := ioutil.ReadFile("test/test.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := Read(bytes.NewReader(data), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := len(strict.DataBlocks.Blocks)
+	// Offset of the ExtraData section.
+	extra := len(data) - 4
+	for _, b := range strict.DataBlocks.Blocks {
+		extra -= int(b.Size)
+	}
+	const linkInfo = 0x4C + 2 + 0x236
+
+	tests := []struct {
+		name       string
+		modify     func(b []byte) []byte
+		warnings   []error
+		blocks     int
+		stringData bool
+	}{
+		{"valid", func(b []byte) []byte { return b }, nil, blocks, true},
+		{"bad-clsid", func(b []byte) []byte { b[4] = 0xFF; return b }, []error{ErrBadCLSID}, blocks, true},
+		{"linkinfo-size", func(b []byte) []byte { b[linkInfo+2] = 0xFF; return b },
+			[]error{ErrSizeExceeded}, blocks, false},
+		{"bad-block", func(b []byte) []byte { b[extra] = 0x05; return b }, []error{ErrBadSize}, blocks - 1, true},
+		{"no-terminal", func(b []byte) []byte { return b[:len(b)-4] }, []error{ErrTruncated}, blocks, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.modify(append([]byte(nil), data...))
+			f, err := Read(bytes.NewReader(b), uint64(len(b)), Lenient())
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(f.Warnings) != len(tt.warnings) {
+				t.Fatalf("Warnings = %v, want %d", f.Warnings, len(tt.warnings))
+			}
+			for i, w := range f.Warnings {
+				if !errors.Is(w.Err, tt.warnings[i]) {
+					t.Errorf("Warnings[%d] = %s, want %v", i, w, tt.warnings[i])
+				}
+			}
+			if got := len(f.DataBlocks.Blocks); got != tt.blocks {
+				t.Errorf("got %d blocks, want %d", got, tt.blocks)
+			}
+			if got := f.StringData.WorkingDir != ""; got != tt.stringData {
+				t.Errorf("WorkingDir = %q, want StringData %v", f.StringData.WorkingDir, tt.stringData)
+			}
+			if got := f.IDList.Path(); got != strict.IDList.Path() {
+				t.Errorf("IDList.Path() = %q, want %q", got, strict.IDList.Path())
+			}
+			// Blocks found after a resync have their offsets in the file.
+			last := strict.DataBlocks.Blocks[blocks-1]
+			if got := f.DataBlocks.Blocks[len(f.DataBlocks.Blocks)-1].Span; got != last.Span {
+				t.Errorf("last block Span = %+v, want %+v", got, last.Span)
+			}
+		})
+	}
+
+	// The header is required.
+	b := append([]byte(nil), data...)
+	b[0] = 0x4D
+	if _, err := Read(bytes.NewReader(b), uint64(len(b)), Lenient()); !errors.Is(err, ErrBadMagic) {
+		t.Errorf("Read() error = %v, want %v", err, ErrBadMagic)
+	}
+}
@@ -0,0 +1,131 @@
+package lnk
+
+import "fmt"
+
+// Limits bounds the memory and work used to parse one file. Parsing fails
+// with ErrLimitExceeded when a limit is reached. Zero fields use the value in
+// DefaultLimits.
+type Limits struct {
+	// MaxAlloc is the total number of bytes allocated for the data of all
+	// sections. In lenient mode the whole file is read into memory first and
+	// must not be larger than MaxAlloc.
+	MaxAlloc int64
+	// MaxItemIDs is the maximum number of ItemIDs in the LinkTargetIDList.
+	MaxItemIDs int
+	// MaxBlocks is the maximum number of ExtraData blocks.
+	MaxBlocks int
+	// MaxStringLength is the maximum number of characters in a StringData
+	// string or a LinkInfo string.
+	MaxStringLength int
+}
+
+// DefaultLimits are used by Read, File and the section functions if no limits
+// are passed. They are large enough for any file created by Windows.
+var DefaultLimits = Limits{
+	MaxAlloc:        16 << 20,
+	MaxItemIDs:      1024,
+	MaxBlocks:       64,
+	MaxStringLength: 0xFFFF,
+}
+
+// WithLimits sets the limits. Use lower limits for untrusted input.
+func WithLimits(l Limits) Option {
+	return func(o *options) {
+		o.limits = l
+	}
+}
+
+// budget tracks the limits while one file is parsed. It also collects the
+// problems that do not stop the parser.
+type budget struct {
+	limits Limits
+	// used is the number of bytes allocated.
+	used int64
+	// items and blocks are the number of ItemIDs and ExtraData blocks read.
+	items  int
+	blocks int
+	// warnings are the invalid strings. The offsets are from the start of the
+	// structure until they are moved with shiftWarnings.
+	warnings []ParseWarning
+}
+
+// newBudget returns a budget for l. Zero fields are set from DefaultLimits.
+func newBudget(l Limits) *budget {
+	if l.MaxAlloc <= 0 {
+		l.MaxAlloc = DefaultLimits.MaxAlloc
+	}
+	if l.MaxItemIDs <= 0 {
+		l.MaxItemIDs = DefaultLimits.MaxItemIDs
+	}
+	if l.MaxBlocks <= 0 {
+		l.MaxBlocks = DefaultLimits.MaxBlocks
+	}
+	if l.MaxStringLength <= 0 {
+		l.MaxStringLength = DefaultLimits.MaxStringLength
+	}
+	return &budget{limits: l}
+}
+
+// alloc reserves n bytes. Call it before allocating data for a size read from
+// the file.
+func (b *budget) alloc(n uint64) error {
+	if n > uint64(b.limits.MaxAlloc-b.used) {
+		return fmt.Errorf("%w - allocating %d bytes with %d of %d bytes used",
+			ErrLimitExceeded, n, b.used, b.limits.MaxAlloc)
+	}
+	b.used += int64(n)
+	return nil
+}
+
+// str returns an error if a string of n characters is too long.
+func (b *budget) str(n int) error {
+	if n > b.limits.MaxStringLength {
+		return fmt.Errorf("%w - string has %d characters, the maximum is %d",
+			ErrLimitExceeded, n, b.limits.MaxStringLength)
+	}
+	return nil
+}
+
+// utf16 decodes the UTF-16 string of a field with decodeUTF16. Invalid
+// UTF-16 is replaced with U+FFFD and added to the warnings. The error is a
+// *ParseError if the string is longer than the limit.
+func (b *budget) utf16(section, field string, offset int64, data []byte) (string, error) {
+	str, err := decodeUTF16(data)
+	if err != nil {
+		b.warn(section, field, offset, err)
+	}
+	if err = b.str(len([]rune(str))); err != nil {
+		return str, parseError(section, field, offset, err)
+	}
+	return str, nil
+}
+
+// warn adds an invalid string to the warnings.
+func (b *budget) warn(section, field string, offset int64, err error) {
+	b.warnings = append(b.warnings, ParseWarning{Section: section, Field: field,
+		Offset: offset, Err: err, Recovery: "replaced the invalid characters with U+FFFD"})
+}
+
+// shiftWarnings adds base to the offsets of the warnings after the first n.
+// Used when a nested structure or section was parsed from its own start.
+func (b *budget) shiftWarnings(n int, base int64) {
+	for i := n; i < len(b.warnings); i++ {
+		b.warnings[i].Offset += base
+	}
+}
+
+// item counts one ItemID and reserves its n bytes.
+func (b *budget) item(n uint64) error {
+	if b.items++; b.items > b.limits.MaxItemIDs {
+		return fmt.Errorf("%w - more than %d ItemIDs", ErrLimitExceeded, b.limits.MaxItemIDs)
+	}
+	return b.alloc(n)
+}
+
+// block counts one ExtraData block and reserves its n bytes.
+func (b *budget) block(n uint64) error {
+	if b.blocks++; b.blocks > b.limits.MaxBlocks {
+		return fmt.Errorf("%w - more than %d ExtraData blocks", ErrLimitExceeded, b.limits.MaxBlocks)
+	}
+	return b.alloc(n)
+}
@@ -0,0 +1,81 @@
+package lnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	data, err := ioutil.ReadFile("test/test.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Read(bytes.NewReader(data), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ExtraData starts before the blocks and the terminal block.
+	extra := len(data) - 4
+	for _, b := range f.DataBlocks.Blocks {
+		extra -= int(b.Size)
+	}
+
+	tests := []struct {
+		name    string
+		modify  func(b []byte) []byte
+		opts    []Option
+		section string
+	}{
+		{"huge-block", func(b []byte) []byte {
+			binary.LittleEndian.PutUint32(b[extra:], 0xFFFFFFF0)
+			return b
+		}, nil, "ExtraData"},
+		{"items", nil, []Option{WithLimits(Limits{MaxItemIDs: 1})}, "LinkTargetIDList"},
+		{"blocks", nil, []Option{WithLimits(Limits{MaxBlocks: 1})}, "ExtraData"},
+		{"strings", nil, []Option{WithLimits(Limits{MaxStringLength: 2})}, "LinkInfo"},
+		// The NetName of the CommonNetworkRelativeLink at 0x340 has 23
+		// characters, shorten the CommonPathSuffix at 0x36C before it.
+		{"network-strings", func([]byte) []byte {
+			b, err := ioutil.ReadFile("test/remote.file.xp.test")
+			if err != nil {
+				t.Fatal(err)
+			}
+			b[0x36C+5] = 0
+			return b
+		}, []Option{WithLimits(Limits{MaxStringLength: 10})}, "CommonNetworkRelativeLink"},
+		{"alloc", nil, []Option{WithLimits(Limits{MaxAlloc: 0x200})}, "LinkTargetIDList"},
+		{"lenient-alloc", nil, []Option{WithLimits(Limits{MaxAlloc: 0x200}), Lenient()}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := append([]byte(nil), data...)
+			if tt.modify != nil {
+				b = tt.modify(b)
+			}
+			_, err := Read(bytes.NewReader(b), uint64(len(b)), tt.opts...)
+			if !errors.Is(err, ErrLimitExceeded) {
+				t.Fatalf("Read() error = %v, want %v", err, ErrLimitExceeded)
+			}
+			var pe *ParseError
+			if tt.section != "" && (!errors.As(err, &pe) || pe.Section != tt.section) {
+				t.Errorf("Read() error = %v, want section %s", err, tt.section)
+			}
+		})
+	}
+}
+
+// TestLimitsLenient checks that the file read by Lenient is not counted twice.
+func TestLimitsLenient(t *testing.T) {
+	data, err := ioutil.ReadFile("test/test.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := []Option{WithLimits(Limits{MaxAlloc: int64(len(data))}), Lenient()}
+	f, err := Read(bytes.NewReader(data), uint64(len(data)), opts...)
+	if err != nil || len(f.Warnings) != 0 {
+		t.Errorf("Read() error = %v, Warnings = %v, want none", err, f.Warnings)
+	}
+}
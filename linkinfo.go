@@ -64,6 +64,9 @@ type LinkInfoSection struct {
 	// Combine with CommonPathSuffix to get the full path to target.
 	LocalBasePath string // Optional
 
+	// LocalBasePathRaw is LocalBasePath before decoding with the code page.
+	LocalBasePathRaw []byte // Optional
+
 	// Optional CommonNetworkRelativeLink, contains information about network
 	// location of the target.
 	NetworkRelativeLink CommonNetworkRelativeLink
@@ -71,6 +74,9 @@ type LinkInfoSection struct {
 	// Null-terminated string. Combine with LocalBasePath to get full path to target.
 	CommonPathSuffix string // Optional
 
+	// CommonPathSuffixRaw is CommonPathSuffix before decoding with the code page.
+	CommonPathSuffixRaw []byte // Optional
+
 	// Null-terminated Unicode string to base path.
 	// Present only VolumeIDAndLocalBasePath is set and LinkInfoHeaderSize >= 0x24.
 	LocalBasePathUnicode string // Optional
@@ -81,6 +87,12 @@ type LinkInfoSection struct {
 
 	// Section's raw bytes.
 	Raw []byte
+
+	// Span is the location of the section.
+	Span Span
+
+	// Code page of the ANSI strings, used to write them back.
+	codePage int
 }
 
 // linkInfoFlags defines the LinkInfoFlags. Only the first two bits are used for now.
@@ -95,14 +107,21 @@ var linkInfoFlags = []string{
 }
 
 // LinkInfo reads the io.Reader and returns a populated LinkInfoSection.
+// Errors are *ParseError. DefaultLimits are used.
 func LinkInfo(r io.Reader, maxSize uint64) (info LinkInfoSection, err error) {
+	return linkInfo(r, maxSize, newBudget(DefaultLimits))
+}
+
+// linkInfo parses the LinkInfo within the limits in b.
+func linkInfo(r io.Reader, maxSize uint64, b *budget) (info LinkInfoSection, err error) {
 
 	// Parse section.
-	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize)
+	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize, b)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: section - %s", err.Error())
+		return info, parseError("LinkInfo", "LinkInfoSize", 0, err)
 	}
 	info.Size = uint32(sectionSize)
+	info.Span = Span{Size: int64(sectionSize)}
 
 	// Save raw bytes.
 	info.Raw = sectionData
@@ -112,7 +131,7 @@ func LinkInfo(r io.Reader, maxSize uint64) (info LinkInfoSection, err error) {
 	// Read LinkInfoHeaderSize.
 	err = binary.Read(sectionReader, binary.LittleEndian, &info.LinkInfoHeaderSize)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: read LinkInfoHeaderSize - %s", err.Error())
+		return info, parseError("LinkInfo", "LinkInfoHeaderSize", 0x04, err)
 	}
 
 	// // If 0x1C no optional fields.
@@ -130,7 +149,7 @@ func LinkInfo(r io.Reader, maxSize uint64) (info LinkInfoSection, err error) {
 	// Read LinkInfoFlags.
 	err = binary.Read(sectionReader, binary.LittleEndian, &info.LinkInfoFlags)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: read LinkInfoFlags - %s", err.Error())
+		return info, parseError("LinkInfo", "LinkInfoFlags", 0x08, err)
 	}
 
 	// fmt.Println("LinkInfoFlags", info.LinkInfoFlags)
@@ -150,34 +169,58 @@ func LinkInfo(r io.Reader, maxSize uint64) (info LinkInfoSection, err error) {
 	// Read VolumeIDOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &info.VolumeIDOffset)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: read VolumeIDOffset - %s", err.Error())
+		return info, parseError("LinkInfo", "VolumeIDOffset", 0x0C, err)
 	}
 	// fmt.Printf("VolumeIDOffset : %v\n", info.VolumeIDOffset)
 
 	// Read LocalBasePathOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &info.LocalBasePathOffset)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: read LocalBasePathOffset - %s", err.Error())
+		return info, parseError("LinkInfo", "LocalBasePathOffset", 0x10, err)
 	}
 	// fmt.Println("LocalBasePathOffset:", info.LocalBasePathOffset)
 
 	// Read CommonNetworkRelativeLinkOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &info.CommonNetworkRelativeLinkOffset)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: read CommonNetworkRelativeLinkOffset - %s", err.Error())
+		return info, parseError("LinkInfo", "CommonNetworkRelativeLinkOffset", 0x14, err)
 	}
 	// fmt.Println("CommonNetworkRelativeLinkOffset:", info.CommonNetworkRelativeLinkOffset)
 
 	// Read CommonPathSuffixOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &info.CommonPathSuffixOffset)
 	if err != nil {
-		return info, fmt.Errorf("lnk.LinkInfo: read CommonPathSuffixOffset - %s", err.Error())
+		return info, parseError("LinkInfo", "CommonPathSuffixOffset", 0x18, err)
 	}
 	// fmt.Println("CommonPathSuffixOffset:", info.CommonPathSuffixOffset)
 
+	// LocalBasePathOffsetUnicode and CommonPathSuffixOffsetUnicode only
+	// exist if LinkInfoHeaderSize >= 0x24.
+	// TODO: Find lnk files that test this.
+	if info.LinkInfoHeaderSize >= 0x24 {
+		// Read LocalBasePathOffsetUnicode.
+		err = binary.Read(sectionReader, binary.LittleEndian, &info.LocalBasePathOffsetUnicode)
+		if err != nil {
+			return info, parseError("LinkInfo", "LocalBasePathOffsetUnicode", 0x1C, err)
+		}
+
+		// Read CommonPathSuffixOffsetUnicode.
+		err = binary.Read(sectionReader, binary.LittleEndian, &info.CommonPathSuffixOffsetUnicode)
+		if err != nil {
+			return info, parseError("LinkInfo", "CommonPathSuffixOffsetUnicode", 0x20, err)
+		}
+	}
+
 	// Read CommonPathSuffix if offset is not zero.
 	if info.CommonPathSuffixOffset != 0x00 {
-		info.CommonPathSuffix = readString(sectionData[info.CommonPathSuffixOffset:])
+		data, err := sectionTail(sectionData, info.CommonPathSuffixOffset)
+		if err != nil {
+			return info, parseError("LinkInfo", "CommonPathSuffixOffset", 0x18, err)
+		}
+		info.CommonPathSuffix, info.CommonPathSuffixRaw = readANSI(data, CodePageAuto)
+		if err = b.str(len(info.CommonPathSuffixRaw)); err != nil {
+			return info, parseError("LinkInfo", "CommonPathSuffix", int64(info.CommonPathSuffixOffset), err)
+		}
 	}
 
 	// If VolumeIDAndLocalBasePath is set then VolumeIDOffset and LocalBasePathOffset
@@ -185,58 +228,53 @@ func LinkInfo(r io.Reader, maxSize uint64) (info LinkInfoSection, err error) {
 	if bitMaskuint32(info.LinkInfoFlags, 0) {
 		// Populate VolumeID based on offset from linkInfo.
 		if info.VolumeIDOffset > info.Size {
-			return info,
-				fmt.Errorf("lnk.LinkInfo: VolumeIDOffset %d larger than LinkInfo size %d",
-					info.VolumeIDOffset, info.Size)
+			return info, parseError("LinkInfo", "VolumeIDOffset", 0x0C,
+				fmt.Errorf("%w - VolumeIDOffset %d larger than LinkInfo size %d",
+					ErrBadOffset, info.VolumeIDOffset, info.Size))
 		}
 
 		// Read VolumeID struct from offset.
 		// Make an io.Reader for bytes starting from that offset.
 		vbuf := bytes.NewReader(sectionData[info.VolumeIDOffset:])
-		vol, err := VolumeID(vbuf, maxSize)
+		n := len(b.warnings)
+		vol, err := volumeID(vbuf, maxSize, b)
 		if err != nil {
-			return info, fmt.Errorf("lnk.LinkInfo: parse VolumeID - %s", err.Error())
+			return info, addOffset(err, int64(info.VolumeIDOffset))
 		}
+		vol.Span.shift(int64(info.VolumeIDOffset))
+		b.shiftWarnings(n, int64(info.VolumeIDOffset))
 		info.VolID = vol
 		// fmt.Println(StructToJSON(info.VolID, true))
 
 		// Read LocalBasePath which is a null-terminated string.
-		info.LocalBasePath = readString(sectionData[info.LocalBasePathOffset:])
+		data, err := sectionTail(sectionData, info.LocalBasePathOffset)
+		if err != nil {
+			return info, parseError("LinkInfo", "LocalBasePathOffset", 0x10, err)
+		}
+		info.LocalBasePath, info.LocalBasePathRaw = readANSI(data, CodePageAuto)
+		if err = b.str(len(info.LocalBasePathRaw)); err != nil {
+			return info, parseError("LinkInfo", "LocalBasePath", int64(info.LocalBasePathOffset), err)
+		}
 		// fmt.Println("LocalBasePath", info.LocalBasePath)
 
-		// LocalBasePathOffsetUnicode and CommonPathSuffixOffsetUnicode only
-		// exist if LinkInfoHeaderSize >= 0x24 and are not zero if
-		// VolumeIDAndLocalBasePath is set.
-		// TODO: Find lnk files that test this.
-		if info.LinkInfoHeaderSize >= 0x24 {
-			// Read LocalBasePathOffsetUnicode.
-			err = binary.Read(sectionReader, binary.LittleEndian, &info.LocalBasePathOffsetUnicode)
-			if err != nil {
-				return info, fmt.Errorf("lnk.LinkInfo: read LocalBasePathOffsetUnicode - %s", err.Error())
-			}
-			// fmt.Println("LocalBasePathOffsetUnicode:", info.LocalBasePathOffsetUnicode)
-
-			// If we have reached here, it's non-zero, so try and read it, if the
-			// offset is not larger than section.
-			if uint32(sectionSize) > info.LocalBasePathOffsetUnicode && info.LocalBasePathOffsetUnicode != 0x00 {
-				// Read unicode string.
-				info.LocalBasePathUnicode = readUnicodeString(sectionData[info.LocalBasePathOffsetUnicode:])
-			}
-			// fmt.Println("LocalBasePathUnicode:", info.LocalBasePathUnicode)
-
-			// Read CommonPathSuffixOffsetUnicode.
-			err = binary.Read(sectionReader, binary.LittleEndian, &info.CommonPathSuffixOffsetUnicode)
+		// Read LocalBasePathUnicode if the offset is not zero and not larger
+		// than the section.
+		if uint32(sectionSize) > info.LocalBasePathOffsetUnicode && info.LocalBasePathOffsetUnicode != 0x00 {
+			info.LocalBasePathUnicode, err = b.utf16("LinkInfo", "LocalBasePathUnicode",
+				int64(info.LocalBasePathOffsetUnicode), sectionData[info.LocalBasePathOffsetUnicode:])
 			if err != nil {
-				return info, fmt.Errorf("lnk.LinkInfo: read CommonPathSuffixOffsetUnicode - %s", err.Error())
+				return info, err
 			}
-			// fmt.Println("CommonPathSuffixOffsetUnicode:", info.CommonPathSuffixOffsetUnicode)
+		}
+	}
 
-			// Read it.
-			if uint32(sectionSize) > info.CommonPathSuffixOffsetUnicode && info.CommonPathSuffixOffsetUnicode != 0x00 {
-				// Read unicode string.
-				info.CommonPathSuffixUnicode = readUnicodeString(sectionData[info.CommonPathSuffixOffsetUnicode:])
-			}
-			// fmt.Println("CommonPathSuffixUnicode:", info.CommonPathSuffixUnicode)
+	// Read CommonPathSuffixUnicode if the offset is not zero and not larger
+	// than the section.
+	if uint32(sectionSize) > info.CommonPathSuffixOffsetUnicode && info.CommonPathSuffixOffsetUnicode != 0x00 {
+		info.CommonPathSuffixUnicode, err = b.utf16("LinkInfo", "CommonPathSuffixUnicode",
+			int64(info.CommonPathSuffixOffsetUnicode), sectionData[info.CommonPathSuffixOffsetUnicode:])
+		if err != nil {
+			return info, err
 		}
 	}
 
@@ -248,14 +286,112 @@ func LinkInfo(r io.Reader, maxSize uint64) (info LinkInfoSection, err error) {
 		// TODO: Find lnks that have this to test.
 		if info.CommonNetworkRelativeLinkOffset != 0x00 {
 			// Create a reader from CommonNetworkRelativeLink data.
-			nbuf := bytes.NewReader(sectionData[info.CommonNetworkRelativeLinkOffset:])
+			data, err := sectionTail(sectionData, info.CommonNetworkRelativeLinkOffset)
+			if err != nil {
+				return info, parseError("LinkInfo", "CommonNetworkRelativeLinkOffset", 0x14, err)
+			}
+			nbuf := bytes.NewReader(data)
 			// And parse it.
-			info.NetworkRelativeLink, _ = CommonNetwork(nbuf, maxSize)
+			n := len(b.warnings)
+			info.NetworkRelativeLink, err = commonNetwork(nbuf, maxSize, b)
+			if err != nil {
+				return info, addOffset(err, int64(info.CommonNetworkRelativeLinkOffset))
+			}
+			info.NetworkRelativeLink.Span.shift(int64(info.CommonNetworkRelativeLinkOffset))
+			b.shiftWarnings(n, int64(info.CommonNetworkRelativeLinkOffset))
 		}
 	}
 	return info, err
 }
 
+// shift adds base to the spans of the section and its structures.
+func (li *LinkInfoSection) shift(base int64) {
+	li.Span.shift(base)
+	li.VolID.Span.shift(base)
+	li.NetworkRelativeLink.Span.shift(base)
+}
+
+// setCodePage decodes the ANSI strings again with the code page.
+func (li *LinkInfoSection) setCodePage(cp int) {
+	li.codePage = cp
+	if li.LocalBasePathRaw != nil {
+		li.LocalBasePath = decodeANSI(li.LocalBasePathRaw, cp)
+	}
+	if li.CommonPathSuffixRaw != nil {
+		li.CommonPathSuffix = decodeANSI(li.CommonPathSuffixRaw, cp)
+	}
+	li.VolID.setCodePage(cp)
+	li.NetworkRelativeLink.setCodePage(cp)
+}
+
+// MarshalBinary returns the LinkInfo structure as it appears on disk. Fields
+// are written based on LinkInfoFlags and the Unicode paths are only written if
+// LinkInfoHeaderSize is at least 0x24. Size and all offsets are calculated.
+func (li LinkInfoSection) MarshalBinary() ([]byte, error) {
+	hasVolume := bitMaskuint32(li.LinkInfoFlags, 0)
+	hasNetwork := bitMaskuint32(li.LinkInfoFlags, 1)
+
+	headerSize := uint32(0x1C)
+	if li.LinkInfoHeaderSize >= 0x24 {
+		headerSize = 0x24
+	}
+
+	// Everything after the header. Offsets are headerSize + current length.
+	var body bytes.Buffer
+	offset := func() uint32 { return headerSize + uint32(body.Len()) }
+
+	var volumeIDOffset, localBasePathOffset uint32
+	if hasVolume {
+		vol, err := li.VolID.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("lnk.LinkInfoSection.MarshalBinary: VolumeID - %w", err)
+		}
+		volumeIDOffset = offset()
+		body.Write(vol)
+		localBasePathOffset = offset()
+		body.Write(nullANSI(li.LocalBasePath, li.LocalBasePathRaw, li.codePage))
+	}
+
+	var networkOffset uint32
+	if hasNetwork {
+		net, err := li.NetworkRelativeLink.MarshalBinary()
+		if err != nil {
+			return nil, fmt.Errorf("lnk.LinkInfoSection.MarshalBinary: CommonNetworkRelativeLink - %w", err)
+		}
+		networkOffset = offset()
+		body.Write(net)
+	}
+
+	// CommonPathSuffix is always present, even if it's empty.
+	commonPathSuffixOffset := offset()
+	body.Write(nullANSI(li.CommonPathSuffix, li.CommonPathSuffixRaw, li.codePage))
+
+	var localBasePathOffsetUnicode, commonPathSuffixOffsetUnicode uint32
+	if headerSize >= 0x24 {
+		if hasVolume {
+			localBasePathOffsetUnicode = offset()
+			body.Write(nullString(li.LocalBasePathUnicode, true))
+		}
+		commonPathSuffixOffsetUnicode = offset()
+		body.Write(nullString(li.CommonPathSuffixUnicode, true))
+	}
+
+	var buf bytes.Buffer
+	buf.Write(uint32Byte(offset()))
+	buf.Write(uint32Byte(headerSize))
+	buf.Write(uint32Byte(li.LinkInfoFlags))
+	buf.Write(uint32Byte(volumeIDOffset))
+	buf.Write(uint32Byte(localBasePathOffset))
+	buf.Write(uint32Byte(networkOffset))
+	buf.Write(uint32Byte(commonPathSuffixOffset))
+	if headerSize >= 0x24 {
+		buf.Write(uint32Byte(localBasePathOffsetUnicode))
+		buf.Write(uint32Byte(commonPathSuffixOffsetUnicode))
+	}
+	buf.Write(body.Bytes())
+	return buf.Bytes(), nil
+}
+
 // String prints LinkInfoSection in a table.
 func (li LinkInfoSection) String() string {
 
@@ -1,9 +1,10 @@
 package lnk
 
 import (
+	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/olekukonko/tablewriter"
@@ -44,11 +45,22 @@ type CommonNetworkRelativeLink struct {
 	// Device name like drive letter. Null-terminated string.
 	DeviceName string
 
+	// NetNameRaw and DeviceNameRaw are the ANSI names before decoding with the
+	// code page.
+	NetNameRaw    []byte
+	DeviceNameRaw []byte
+
 	// Unicode string. Must not exist if NetNameOffset > 0x14.
 	NetNameUnicode string
 
 	// Unicode string. Must not exist if NetNameOffset > 0x14.
 	DeviceNameUnicode string
+
+	// Span is the location of the CommonNetworkRelativeLink.
+	Span Span
+
+	// Code page of the ANSI names, used to write them back.
+	codePage int
 }
 
 // commonNetworkRelativeLinkFlags is the index for CommonNetworkRelativeLinkFlags.
@@ -62,53 +74,55 @@ var commonNetworkRelativeLinkFlags = []string{
 	"ValidNetType", // Bit 1
 }
 
+// networkProviders maps NetworkProviderType values to their names.
+var networkProviders = map[uint32]string{
+	0x001A0000: "WNNC_NET_AVID",
+	0x001B0000: "WNNC_NET_DOCUSPACE",
+	0x001C0000: "WNNC_NET_MANGOSOFT",
+	0x001D0000: "WNNC_NET_SERNET",
+	0X001E0000: "WNNC_NET_RIVERFRONT1",
+	0x001F0000: "WNNC_NET_RIVERFRONT2",
+	0x00200000: "WNNC_NET_DECORB",
+	0x00210000: "WNNC_NET_PROTSTOR",
+	0x00220000: "WNNC_NET_FJ_REDIR",
+	0x00230000: "WNNC_NET_DISTINCT",
+	0x00240000: "WNNC_NET_TWINS",
+	0x00250000: "WNNC_NET_RDR2SAMPLE",
+	0x00260000: "WNNC_NET_CSC",
+	0x00270000: "WNNC_NET_3IN1",
+	0x00290000: "WNNC_NET_EXTENDNET",
+	0x002A0000: "WNNC_NET_STAC",
+	0x002B0000: "WNNC_NET_FOXBAT",
+	0x002C0000: "WNNC_NET_YAHOO",
+	0x002D0000: "WNNC_NET_EXIFS",
+	0x002E0000: "WNNC_NET_DAV",
+	0x002F0000: "WNNC_NET_KNOWARE",
+	0x00300000: "WNNC_NET_OBJECT_DIRE",
+	0x00310000: "WNNC_NET_MASFAX",
+	0x00320000: "WNNC_NET_HOB_NFS",
+	0x00330000: "WNNC_NET_SHIVA",
+	0x00340000: "WNNC_NET_IBMAL",
+	0x00350000: "WNNC_NET_LOCK",
+	0x00360000: "WNNC_NET_TERMSRV",
+	0x00370000: "WNNC_NET_SRT",
+	0x00380000: "WNNC_NET_QUINCY",
+	0x00390000: "WNNC_NET_OPENAFS",
+	0X003A0000: "WNNC_NET_AVID1",
+	0x003B0000: "WNNC_NET_DFS",
+	0x003C0000: "WNNC_NET_KWNP",
+	0x003D0000: "WNNC_NET_ZENWORKS",
+	0x003E0000: "WNNC_NET_DRIVEONWEB",
+	0x003F0000: "WNNC_NET_VMWARE",
+	0x00400000: "WNNC_NET_RSFX",
+	0x00410000: "WNNC_NET_MFILES",
+	0x00420000: "WNNC_NET_MS_NFS",
+	0x00430000: "WNNC_NET_GOOGLE",
+}
+
 // networkProviderType returns a string representing the network provider based
 // on the value of the NetworkProviderType uint32 and "" for invalid values.
 func networkProviderType(index uint32) string {
-	networkMap := map[uint32]string{
-		0x001A0000: "WNNC_NET_AVID",
-		0x001B0000: "WNNC_NET_DOCUSPACE",
-		0x001C0000: "WNNC_NET_MANGOSOFT",
-		0x001D0000: "WNNC_NET_SERNET",
-		0X001E0000: "WNNC_NET_RIVERFRONT1",
-		0x001F0000: "WNNC_NET_RIVERFRONT2",
-		0x00200000: "WNNC_NET_DECORB",
-		0x00210000: "WNNC_NET_PROTSTOR",
-		0x00220000: "WNNC_NET_FJ_REDIR",
-		0x00230000: "WNNC_NET_DISTINCT",
-		0x00240000: "WNNC_NET_TWINS",
-		0x00250000: "WNNC_NET_RDR2SAMPLE",
-		0x00260000: "WNNC_NET_CSC",
-		0x00270000: "WNNC_NET_3IN1",
-		0x00290000: "WNNC_NET_EXTENDNET",
-		0x002A0000: "WNNC_NET_STAC",
-		0x002B0000: "WNNC_NET_FOXBAT",
-		0x002C0000: "WNNC_NET_YAHOO",
-		0x002D0000: "WNNC_NET_EXIFS",
-		0x002E0000: "WNNC_NET_DAV",
-		0x002F0000: "WNNC_NET_KNOWARE",
-		0x00300000: "WNNC_NET_OBJECT_DIRE",
-		0x00310000: "WNNC_NET_MASFAX",
-		0x00320000: "WNNC_NET_HOB_NFS",
-		0x00330000: "WNNC_NET_SHIVA",
-		0x00340000: "WNNC_NET_IBMAL",
-		0x00350000: "WNNC_NET_LOCK",
-		0x00360000: "WNNC_NET_TERMSRV",
-		0x00370000: "WNNC_NET_SRT",
-		0x00380000: "WNNC_NET_QUINCY",
-		0x00390000: "WNNC_NET_OPENAFS",
-		0X003A0000: "WNNC_NET_AVID1",
-		0x003B0000: "WNNC_NET_DFS",
-		0x003C0000: "WNNC_NET_KWNP",
-		0x003D0000: "WNNC_NET_ZENWORKS",
-		0x003E0000: "WNNC_NET_DRIVEONWEB",
-		0x003F0000: "WNNC_NET_VMWARE",
-		0x00400000: "WNNC_NET_RSFX",
-		0x00410000: "WNNC_NET_MFILES",
-		0x00420000: "WNNC_NET_MS_NFS",
-		0x00430000: "WNNC_NET_GOOGLE",
-	}
-	val, exists := networkMap[index]
+	val, exists := networkProviders[index]
 	if exists {
 		return val
 	}
@@ -116,15 +130,36 @@ func networkProviderType(index uint32) string {
 	return uint32StrHex(index)
 }
 
+// networkProviderValue is the reverse of networkProviderType. Empty or
+// unparsable strings return 0.
+func networkProviderValue(s string) uint32 {
+	for k, v := range networkProviders {
+		if v == s {
+			return k
+		}
+	}
+	val, err := strconv.ParseUint(strings.TrimPrefix(s, "0x"), 16, 32)
+	if err != nil {
+		return 0
+	}
+	return uint32(val)
+}
+
 // CommonNetwork reads the section data and populates a CommonNetworkRelativeLink.
-// Section 2.3.2 in docs.
+// Section 2.3.2 in docs. Errors are *ParseError. DefaultLimits are used.
 func CommonNetwork(r io.Reader, maxSize uint64) (c CommonNetworkRelativeLink, err error) {
+	return commonNetwork(r, maxSize, newBudget(DefaultLimits))
+}
+
+// commonNetwork parses the CommonNetworkRelativeLink within the limits in b.
+func commonNetwork(r io.Reader, maxSize uint64, b *budget) (c CommonNetworkRelativeLink, err error) {
 	// Read the section.
-	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize)
+	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize, b)
 	if err != nil {
-		return c, fmt.Errorf("golnk.CommonNetwork: read CommonNetwork section - %s", err.Error())
+		return c, parseError("CommonNetworkRelativeLink", "CommonNetworkRelativeLinkSize", 0, err)
 	}
 	c.Size = uint32(sectionSize)
+	c.Span = Span{Size: int64(sectionSize)}
 
 	// fmt.Println("------")
 
@@ -135,7 +170,7 @@ func CommonNetwork(r io.Reader, maxSize uint64) (c CommonNetworkRelativeLink, er
 	// Read CommonNetworkRelativeLinkFlags.
 	err = binary.Read(sectionReader, binary.LittleEndian, &c.CommonNetworkRelativeLinkFlags)
 	if err != nil {
-		return c, fmt.Errorf("golnk.CommonNetwork: read CommonNetworkRelativeLinkFlags - %s", err.Error())
+		return c, parseError("CommonNetworkRelativeLink", "CommonNetworkRelativeLinkFlags", 0x04, err)
 	}
 	// fmt.Println("CommonNetworkRelativeLinkFlags", c.CommonNetworkRelativeLinkFlags)
 
@@ -151,14 +186,14 @@ func CommonNetwork(r io.Reader, maxSize uint64) (c CommonNetworkRelativeLink, er
 	// Read NetNameOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &c.NetNameOffset)
 	if err != nil {
-		return c, fmt.Errorf("golnk.CommonNetwork: read NetNameOffset - %s", err.Error())
+		return c, parseError("CommonNetworkRelativeLink", "NetNameOffset", 0x08, err)
 	}
 	// fmt.Println("NetNameOffset", c.NetNameOffset)
 
 	// Read DeviceNameOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &c.DeviceNameOffset)
 	if err != nil {
-		return c, fmt.Errorf("golnk.CommonNetwork: read DeviceNameOffset - %s", err.Error())
+		return c, parseError("CommonNetworkRelativeLink", "DeviceNameOffset", 0x0C, err)
 	}
 	// fmt.Println("DeviceNameOffset", c.DeviceNameOffset)
 
@@ -166,7 +201,7 @@ func CommonNetwork(r io.Reader, maxSize uint64) (c CommonNetworkRelativeLink, er
 	var nType uint32
 	err = binary.Read(sectionReader, binary.LittleEndian, &nType)
 	if err != nil {
-		return c, fmt.Errorf("golnk.CommonNetwork: read NetworkProviderType - %s", err.Error())
+		return c, parseError("CommonNetworkRelativeLink", "NetworkProviderType", 0x10, err)
 	}
 	// fmt.Println("nType", nType)
 	// fmt.Printf("%x\n", nType)
@@ -182,25 +217,114 @@ func CommonNetwork(r io.Reader, maxSize uint64) (c CommonNetworkRelativeLink, er
 		// Read NetNameOffsetUnicode.
 		err = binary.Read(sectionReader, binary.LittleEndian, &c.NetNameOffsetUnicode)
 		if err != nil {
-			return c, fmt.Errorf("golnk.CommonNetwork: read NetNameOffsetUnicode - %s", err.Error())
+			return c, parseError("CommonNetworkRelativeLink", "NetNameOffsetUnicode", 0x14, err)
 		}
-		// fmt.Println("NetNameOffsetUnicode", c.NetNameOffsetUnicode)
 
 		// Read DeviceNameOffsetUnicode.
 		err = binary.Read(sectionReader, binary.LittleEndian, &c.DeviceNameOffsetUnicode)
 		if err != nil {
-			return c, fmt.Errorf("golnk.CommonNetwork: read DeviceNameOffsetUnicode - %s", err.Error())
+			return c, parseError("CommonNetworkRelativeLink", "DeviceNameOffsetUnicode", 0x18, err)
+		}
+
+		if c.NetNameOffsetUnicode != 0 && c.NetNameOffsetUnicode < c.Size {
+			c.NetNameUnicode, err = b.utf16("CommonNetworkRelativeLink", "NetNameUnicode",
+				int64(c.NetNameOffsetUnicode), sectionData[c.NetNameOffsetUnicode:])
+			if err != nil {
+				return c, err
+			}
+		}
+		if c.DeviceNameOffsetUnicode != 0 && c.DeviceNameOffsetUnicode < c.Size {
+			c.DeviceNameUnicode, err = b.utf16("CommonNetworkRelativeLink", "DeviceNameUnicode",
+				int64(c.DeviceNameOffsetUnicode), sectionData[c.DeviceNameOffsetUnicode:])
+			if err != nil {
+				return c, err
+			}
+		}
+	}
+
+	// Read NetName from NetNameOffset as a null-terminated string.
+	if c.NetNameOffset < c.Size {
+		c.NetName, c.NetNameRaw = readANSI(sectionData[c.NetNameOffset:], CodePageAuto)
+		if err = b.str(len(c.NetNameRaw)); err != nil {
+			return c, parseError("CommonNetworkRelativeLink", "NetName", int64(c.NetNameOffset), err)
+		}
+	}
+
+	// DeviceName is only there if ValidDevice is set.
+	if bitMaskuint32(c.CommonNetworkRelativeLinkFlags, 0) &&
+		c.DeviceNameOffset != 0 && c.DeviceNameOffset < c.Size {
+		c.DeviceName, c.DeviceNameRaw = readANSI(sectionData[c.DeviceNameOffset:], CodePageAuto)
+		if err = b.str(len(c.DeviceNameRaw)); err != nil {
+			return c, parseError("CommonNetworkRelativeLink", "DeviceName", int64(c.DeviceNameOffset), err)
 		}
-		// fmt.Println("DeviceNameOffsetUnicode", c.DeviceNameOffsetUnicode)
-	} else {
-		// fmt.Printf("NetNameOffset 0x%x smaller than or equal to 0x14, skipping Unicode fields.\n", c.NetNameOffset)
-		// Read NetName from NetNameOffset as a null-terminated string.
-		c.NetName = readString(sectionData[c.NetNameOffset:])
-		// fmt.Println("NetName", c.NetName)
 	}
 	return c, err
 }
 
+// setCodePage decodes the ANSI names again with the code page.
+func (c *CommonNetworkRelativeLink) setCodePage(cp int) {
+	c.codePage = cp
+	if c.NetNameRaw != nil {
+		c.NetName = decodeANSI(c.NetNameRaw, cp)
+	}
+	if c.DeviceNameRaw != nil {
+		c.DeviceName = decodeANSI(c.DeviceNameRaw, cp)
+	}
+}
+
+// MarshalBinary returns the CommonNetworkRelativeLink as it appears on disk.
+// The Unicode names are written if NetNameOffset is larger than 0x14. Size and
+// offsets are calculated.
+func (c CommonNetworkRelativeLink) MarshalBinary() ([]byte, error) {
+	validDevice := bitMaskuint32(c.CommonNetworkRelativeLinkFlags, 0)
+	isUnicode := c.NetNameOffset > 0x14
+
+	// Offset of the first string is the end of the fixed fields.
+	offset := uint32(0x14)
+	if isUnicode {
+		offset = 0x1C
+	}
+
+	// Strings after the fixed fields, in the same order as the offsets.
+	var strs bytes.Buffer
+	netNameOffset := offset
+	strs.Write(nullANSI(c.NetName, c.NetNameRaw, c.codePage))
+
+	var deviceNameOffset uint32
+	if validDevice {
+		deviceNameOffset = offset + uint32(strs.Len())
+		strs.Write(nullANSI(c.DeviceName, c.DeviceNameRaw, c.codePage))
+	}
+
+	var netNameOffsetUnicode, deviceNameOffsetUnicode uint32
+	if isUnicode {
+		netNameOffsetUnicode = offset + uint32(strs.Len())
+		strs.Write(nullString(c.NetNameUnicode, true))
+		if validDevice {
+			deviceNameOffsetUnicode = offset + uint32(strs.Len())
+			strs.Write(nullString(c.DeviceNameUnicode, true))
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.Write(uint32Byte(offset + uint32(strs.Len())))
+	buf.Write(uint32Byte(c.CommonNetworkRelativeLinkFlags))
+	buf.Write(uint32Byte(netNameOffset))
+	buf.Write(uint32Byte(deviceNameOffset))
+	// NetworkProviderType must be zero if ValidNetType is not set.
+	if bitMaskuint32(c.CommonNetworkRelativeLinkFlags, 1) {
+		buf.Write(uint32Byte(networkProviderValue(c.NetworkProviderType)))
+	} else {
+		buf.Write(uint32Byte(0))
+	}
+	if isUnicode {
+		buf.Write(uint32Byte(netNameOffsetUnicode))
+		buf.Write(uint32Byte(deviceNameOffsetUnicode))
+	}
+	buf.Write(strs.Bytes())
+	return buf.Bytes(), nil
+}
+
 // String prints CommonNetworkRelativeLink in a table.
 func (c CommonNetworkRelativeLink) String() string {
 	var sb, flags strings.Builder
@@ -1,6 +1,7 @@
 package lnk
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"fmt"
@@ -33,6 +34,16 @@ type VolID struct {
 
 	// VolumeLabel in either ASCII-HEX or Unicode.
 	VolumeLabel string
+
+	// VolumeLabelRaw is the ANSI VolumeLabel before decoding with the code
+	// page. Empty if the label is Unicode.
+	VolumeLabelRaw []byte
+
+	// Span is the location of the VolumeID.
+	Span Span
+
+	// Code page of the ANSI label, used to write it back.
+	codePage int
 }
 
 // Different DriveTypes. The value of field is the index to this slice.
@@ -46,14 +57,21 @@ var driveType = []string{
 	"DRIVE_RAMDISK",
 }
 
-// VolumeID reads the VolID struct.
+// VolumeID reads the VolID struct. Errors are *ParseError. DefaultLimits are
+// used.
 func VolumeID(r io.Reader, maxSize uint64) (v VolID, err error) {
+	return volumeID(r, maxSize, newBudget(DefaultLimits))
+}
+
+// volumeID parses the VolumeID within the limits in b.
+func volumeID(r io.Reader, maxSize uint64, b *budget) (v VolID, err error) {
 	// Read the section.
-	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize)
+	sectionData, sectionReader, sectionSize, err := readSection(r, 4, maxSize, b)
 	if err != nil {
-		return v, fmt.Errorf("golnk.VolumeID: read VolumeID section - %s", err.Error())
+		return v, parseError("VolumeID", "VolumeIDSize", 0, err)
 	}
-	_ = sectionSize
+	v.Size = uint32(sectionSize)
+	v.Span = Span{Size: int64(sectionSize)}
 	// fmt.Printf("Read section volumeID. %d bytes.\n", sectionSize)
 	// fmt.Println(hex.Dump(sectionData))
 
@@ -61,7 +79,7 @@ func VolumeID(r io.Reader, maxSize uint64) (v VolID, err error) {
 	var dt uint32
 	err = binary.Read(sectionReader, binary.LittleEndian, &dt)
 	if err != nil {
-		return v, fmt.Errorf("golnk.VolumeID: read VolumeID.DriveType - %s", err.Error())
+		return v, parseError("VolumeID", "DriveType", 0x04, err)
 	}
 	// Check if it's a valid DriveType.
 	if dt >= uint32(len(driveType)) {
@@ -77,7 +95,7 @@ func VolumeID(r io.Reader, maxSize uint64) (v VolID, err error) {
 	var sr [4]byte
 	err = binary.Read(sectionReader, binary.LittleEndian, &sr)
 	if err != nil {
-		return v, fmt.Errorf("golnk.VolumeID: read VolumeID.DriveSerialNumber - %s", err.Error())
+		return v, parseError("VolumeID", "DriveSerialNumber", 0x08, err)
 	}
 	v.DriveSerialNumber = "0x" + hex.EncodeToString(sr[:])
 
@@ -86,7 +104,7 @@ func VolumeID(r io.Reader, maxSize uint64) (v VolID, err error) {
 	// Read VolumeLabelOffset.
 	err = binary.Read(sectionReader, binary.LittleEndian, &v.VolumeLabelOffset)
 	if err != nil {
-		return v, fmt.Errorf("golnk.VolumeID: read VolumeID.VolumeLabelOffset - %s", err.Error())
+		return v, parseError("VolumeID", "VolumeLabelOffset", 0x0C, err)
 	}
 	// fmt.Println("VolumeID.VolumeLabelOffset:", v.VolumeLabelOffset)
 
@@ -95,8 +113,14 @@ func VolumeID(r io.Reader, maxSize uint64) (v VolID, err error) {
 	// If it is 0x14, ignore this and read the next uint32 for VolumeLabelOffsetUnicode.
 	if v.VolumeLabelOffset != 0x14 {
 		// Read a null-terminated string from sectionData[v.VolumeLabelOffset:].
-		str := readString(sectionData[v.VolumeLabelOffset:])
-		v.VolumeLabel = str
+		data, err := sectionTail(sectionData, v.VolumeLabelOffset)
+		if err != nil {
+			return v, parseError("VolumeID", "VolumeLabelOffset", 0x0C, err)
+		}
+		v.VolumeLabel, v.VolumeLabelRaw = readANSI(data, CodePageAuto)
+		if err = b.str(len(v.VolumeLabelRaw)); err != nil {
+			return v, parseError("VolumeID", "VolumeLabel", int64(v.VolumeLabelOffset), err)
+		}
 		// fmt.Println("VolumeLabel", str)
 
 		// Because we read VolumeLabel manually, VolumeLabelOffsetUnicode must
@@ -108,14 +132,72 @@ func VolumeID(r io.Reader, maxSize uint64) (v VolID, err error) {
 	// If v.VolumeLabelOffset is 0x14, it means we need to read a uint32
 	// to get VolumeLabelOffsetUnicode and read a unicode string there.
 	err = binary.Read(sectionReader, binary.LittleEndian, &v.VolumeLabelOffsetUnicode)
+	if err != nil {
+		return v, parseError("VolumeID", "VolumeLabelOffsetUnicode", 0x10, err)
+	}
 	// fmt.Println("v.VolumeLabelOffsetUnicode", v.VolumeLabelOffsetUnicode)
 
 	// Read a unicode string from that offset.
-	unicodeStr := readUnicodeString(sectionData[v.VolumeLabelOffsetUnicode:])
-	v.VolumeLabel = unicodeStr
+	data, err := sectionTail(sectionData, v.VolumeLabelOffsetUnicode)
+	if err != nil {
+		return v, parseError("VolumeID", "VolumeLabelOffsetUnicode", 0x10, err)
+	}
+	v.VolumeLabel, err = b.utf16("VolumeID", "VolumeLabelUnicode", int64(v.VolumeLabelOffsetUnicode), data)
+	if err != nil {
+		return v, err
+	}
 	// fmt.Println("VolumeLabelUnicode", v.VolumeLabel)
 
-	return v, err
+	return v, nil
+}
+
+// setCodePage decodes the ANSI label again with the code page.
+func (v *VolID) setCodePage(cp int) {
+	v.codePage = cp
+	if v.VolumeLabelRaw != nil {
+		v.VolumeLabel = decodeANSI(v.VolumeLabelRaw, cp)
+	}
+}
+
+// MarshalBinary returns the VolumeID as it appears on disk. The label is
+// stored in Unicode if VolumeLabelOffset is 0x14 and in ANSI otherwise. Size
+// and offsets are calculated.
+func (v VolID) MarshalBinary() ([]byte, error) {
+	// Reverse the DriveType string. DRIVE_INVALID and unknown values are
+	// stored as DRIVE_UNKNOWN.
+	var dt uint32
+	for i, d := range driveType {
+		if d == v.DriveType {
+			dt = uint32(i)
+			break
+		}
+	}
+
+	// DriveSerialNumber is the hex encoded bytes as they appear in the file.
+	var sr [4]byte
+	if v.DriveSerialNumber != "" {
+		b, err := hex.DecodeString(strings.TrimPrefix(v.DriveSerialNumber, "0x"))
+		if err != nil || len(b) != 4 {
+			return nil, fmt.Errorf("golnk.VolID.MarshalBinary: invalid DriveSerialNumber - got %s", v.DriveSerialNumber)
+		}
+		copy(sr[:], b)
+	}
+
+	// Everything after the size field.
+	var body bytes.Buffer
+	body.Write(uint32Byte(dt))
+	body.Write(sr[:])
+	if v.VolumeLabelOffset == 0x14 {
+		// VolumeLabelOffset is ignored and VolumeLabelOffsetUnicode points
+		// right after itself.
+		body.Write(uint32Byte(0x14))
+		body.Write(uint32Byte(0x14))
+		body.Write(nullString(v.VolumeLabel, true))
+	} else {
+		body.Write(uint32Byte(0x10))
+		body.Write(nullANSI(v.VolumeLabel, v.VolumeLabelRaw, v.codePage))
+	}
+	return append(uint32Byte(uint32(body.Len()+4)), body.Bytes()...), nil
 }
 
 // String prints VolumeID in a table.
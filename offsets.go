@@ -0,0 +1,364 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"unicode/utf16"
+)
+
+// Span is the location of a structure or field. Offset is from the start of
+// the file if the structure was parsed by Read and File, otherwise from the
+// start of the data passed to the section function. Structures that were not
+// parsed have a zero Size.
+type Span struct {
+	Offset int64
+	Size   int64
+}
+
+// End returns the offset after the last byte.
+func (s Span) End() int64 {
+	return s.Offset + s.Size
+}
+
+// shift adds base to the offset.
+func (s *Span) shift(base int64) {
+	s.Offset += base
+}
+
+// Field is the location of a field or structure of the file.
+type Field struct {
+	// Name is the structure and field, e.g. LinkInfo.VolumeID.DriveSerialNumber.
+	// Structures have the name of the structure, e.g. LinkInfo.VolumeID, and
+	// contain their fields.
+	Name string
+	Span
+}
+
+// Offsets returns the location of every parsed structure and field of the
+// file, sorted by offset. A structure comes before its fields. Fields of the
+// sections that could not be parsed in lenient mode are not included.
+func (f LnkFile) Offsets() []Field {
+	var fields []Field
+	for _, fi := range f.fields() {
+		fields = append(fields, fi.Field)
+	}
+	return fields
+}
+
+// layoutField is a Field with details for AnnotatedDump.
+type layoutField struct {
+	Field
+	// note is printed after the name, e.g. the shell item type.
+	note string
+	// structure is true if the field contains other fields.
+	structure bool
+}
+
+// fieldLayout collects the fields of the sections.
+type fieldLayout struct {
+	fields []layoutField
+	// raw is the LinkInfo section used to find the size of its strings.
+	raw  []byte
+	base int64
+}
+
+// add adds a field. name is prefixed with the structure.
+func (l *fieldLayout) add(structure string, offset, size int64, name string) {
+	l.fields = append(l.fields, layoutField{Field: Field{structure + "." + name, Span{offset, size}}})
+}
+
+// structure adds a structure.
+func (l *fieldLayout) structure(name string, s Span) {
+	l.fields = append(l.fields, layoutField{Field: Field{name, s}, structure: true})
+}
+
+// str adds the null-terminated string at offset in the LinkInfo. end is the
+// end of the structure that contains the string.
+func (l *fieldLayout) str(structure, name string, offset, end int64, unicode bool) {
+	from, to := offset-l.base, end-l.base
+	if to > int64(len(l.raw)) {
+		to = int64(len(l.raw))
+	}
+	if from < 0 || from >= to {
+		return
+	}
+	data := l.raw[from:to]
+	size := int64(len(data))
+	if !unicode {
+		if i := bytes.IndexByte(data, 0x00); i >= 0 {
+			size = int64(i) + 1
+		}
+	} else {
+		for i := 0; i+1 < len(data); i += 2 {
+			if data[i] == 0x00 && data[i+1] == 0x00 {
+				size = int64(i) + 2
+				break
+			}
+		}
+	}
+	l.add(structure, offset, size, name)
+}
+
+// headerFields are the fields of the ShellLinkHeader.
+var headerFields = []Field{
+	{"HeaderSize", Span{0x00, 4}},
+	{"LinkCLSID", Span{0x04, 16}},
+	{"LinkFlags", Span{0x14, 4}},
+	{"FileAttributes", Span{0x18, 4}},
+	{"CreationTime", Span{0x1C, 8}},
+	{"AccessTime", Span{0x24, 8}},
+	{"WriteTime", Span{0x2C, 8}},
+	{"FileSize", Span{0x34, 4}},
+	{"IconIndex", Span{0x38, 4}},
+	{"ShowCommand", Span{0x3C, 4}},
+	{"HotKey", Span{0x40, 2}},
+	{"Reserved1", Span{0x42, 2}},
+	{"Reserved2", Span{0x44, 4}},
+	{"Reserved3", Span{0x48, 4}},
+}
+
+// trackerFields are the fields of TrackerDataBlock after the signature.
+var trackerFields = []Field{
+	{"Length", Span{0x00, 4}},
+	{"Version", Span{0x04, 4}},
+	{"MachineID", Span{0x08, 16}},
+	{"DroidVolumeID", Span{0x18, 16}},
+	{"DroidFileID", Span{0x28, 16}},
+	{"DroidBirthVolumeID", Span{0x38, 16}},
+	{"DroidBirthFileID", Span{0x48, 16}},
+}
+
+// consoleFields are the fields of ConsoleDataBlock after the signature.
+var consoleFields = []Field{
+	{"FillAttributes", Span{0x00, 2}},
+	{"PopupFillAttributes", Span{0x02, 2}},
+	{"ScreenBufferSizeX", Span{0x04, 2}},
+	{"ScreenBufferSizeY", Span{0x06, 2}},
+	{"WindowSizeX", Span{0x08, 2}},
+	{"WindowSizeY", Span{0x0A, 2}},
+	{"WindowOriginX", Span{0x0C, 2}},
+	{"WindowOriginY", Span{0x0E, 2}},
+	{"Unused1", Span{0x10, 4}},
+	{"Unused2", Span{0x14, 4}},
+	{"FontSize", Span{0x18, 4}},
+	{"FontFamily", Span{0x1C, 4}},
+	{"FontWeight", Span{0x20, 4}},
+	{"FaceName", Span{0x24, 64}},
+	{"CursorSize", Span{0x64, 4}},
+	{"FullScreen", Span{0x68, 4}},
+	{"QuickEdit", Span{0x6C, 4}},
+	{"InsertMode", Span{0x70, 4}},
+	{"AutoPosition", Span{0x74, 4}},
+	{"HistoryBufferSize", Span{0x78, 4}},
+	{"NumberOfHistoryBuffers", Span{0x7C, 4}},
+	{"HistoryNoDup", Span{0x80, 4}},
+	{"ColorTable", Span{0x84, 64}},
+}
+
+// environmentFields are the fields of EnvironmentVariableDataBlock and
+// IconEnvironmentDataBlock after the signature.
+var environmentFields = []Field{
+	{"TargetAnsi", Span{0x00, 260}},
+	{"TargetUnicode", Span{0x104, 520}},
+}
+
+// blockFields are the fields of the parsed blocks after the signature. The
+// other blocks have one Data field.
+var blockFields = map[uint32][]Field{
+	0xA0000001: environmentFields,
+	0xA0000002: consoleFields,
+	0xA0000003: trackerFields,
+	0xA0000004: {{"CodePage", Span{0x00, 4}}},
+	0xA0000006: {{"DarwinDataAnsi", Span{0x00, 260}}, {"DarwinDataUnicode", Span{0x104, 520}}},
+	0xA0000005: {{"SpecialFolderID", Span{0x00, 4}}, {"Offset", Span{0x04, 4}}},
+	0xA0000007: environmentFields,
+	0xA000000B: {{"KnownFolderID", Span{0x00, 16}}, {"Offset", Span{0x10, 4}}},
+}
+
+// fields returns the structures and fields of f sorted by offset.
+func (f LnkFile) fields() []layoutField {
+	l := &fieldLayout{}
+	if h := f.Header.Span; h.Size > 0 {
+		l.structure("ShellLinkHeader", h)
+		for _, fi := range headerFields {
+			l.add("ShellLinkHeader", h.Offset+fi.Offset, fi.Size, fi.Name)
+		}
+	}
+	if f.Header.LinkFlags["HasLinkTargetIDList"] {
+		f.IDList.fields(l)
+	}
+	if f.Header.LinkFlags["HasLinkInfo"] && f.LinkInfo.Span.Size > 0 {
+		f.LinkInfo.fields(l)
+	}
+	if f.StringData.Span.Size > 0 {
+		f.StringData.fields(l, f.Header.LinkFlags)
+	}
+	f.DataBlocks.fields(l)
+
+	// Structures are larger than their first field.
+	sort.SliceStable(l.fields, func(i, j int) bool {
+		a, b := l.fields[i], l.fields[j]
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.Size > b.Size
+	})
+	return l.fields
+}
+
+// fields adds the structures and fields of the IDList. The ItemIDs are added
+// even if the section could not be parsed.
+func (li LinkTargetIDListSection) fields(l *fieldLayout) {
+	const s = "LinkTargetIDList"
+	if li.Span.Size > 0 {
+		l.structure(s, li.Span)
+		l.add(s, li.Span.Offset, 2, "IDListSize")
+	}
+	for i, it := range li.List.ItemIDList {
+		name := fmt.Sprintf("%s.IDList.ItemID[%d]", s, i)
+		l.structure(name, it.Span)
+		l.add(name, it.Span.Offset, 2, "ItemIDSize")
+		l.add(name, it.Span.Offset+2, it.Span.Size-2, "Data")
+		if it.Item != nil {
+			l.fields[len(l.fields)-1].note = it.Item.TypeName()
+		}
+	}
+	if li.Span.Size > 0 {
+		l.add(s, li.Span.End()-2, 2, "IDList.TerminalID")
+	}
+}
+
+// fields adds the structures and fields of the LinkInfo.
+func (li LinkInfoSection) fields(l *fieldLayout) {
+	const s = "LinkInfo"
+	pos, end := li.Span.Offset, li.Span.End()
+	l.raw, l.base = li.Raw, pos
+	l.structure(s, li.Span)
+	for i, name := range []string{"LinkInfoSize", "LinkInfoHeaderSize", "LinkInfoFlags",
+		"VolumeIDOffset", "LocalBasePathOffset", "CommonNetworkRelativeLinkOffset",
+		"CommonPathSuffixOffset"} {
+		l.add(s, pos+int64(i*4), 4, name)
+	}
+	if li.LinkInfoHeaderSize >= 0x24 {
+		l.add(s, pos+0x1C, 4, "LocalBasePathOffsetUnicode")
+		l.add(s, pos+0x20, 4, "CommonPathSuffixOffsetUnicode")
+	}
+	str := func(name string, offset uint32, unicode bool) {
+		if offset != 0 {
+			l.str(s, name, pos+int64(offset), end, unicode)
+		}
+	}
+
+	if bitMaskuint32(li.LinkInfoFlags, 0) {
+		if li.VolID.Span.Size > 0 {
+			li.VolID.fields(l)
+		}
+		str("LocalBasePath", li.LocalBasePathOffset, false)
+		str("LocalBasePathUnicode", li.LocalBasePathOffsetUnicode, true)
+	}
+	if bitMaskuint32(li.LinkInfoFlags, 1) && li.NetworkRelativeLink.Span.Size > 0 {
+		li.NetworkRelativeLink.fields(l)
+	}
+	str("CommonPathSuffix", li.CommonPathSuffixOffset, false)
+	str("CommonPathSuffixUnicode", li.CommonPathSuffixOffsetUnicode, true)
+}
+
+// fields adds the structure and fields of the VolumeID.
+func (v VolID) fields(l *fieldLayout) {
+	const s = "LinkInfo.VolumeID"
+	pos, end := v.Span.Offset, v.Span.End()
+	l.structure(s, v.Span)
+	l.add(s, pos, 4, "VolumeIDSize")
+	l.add(s, pos+0x04, 4, "DriveType")
+	l.add(s, pos+0x08, 4, "DriveSerialNumber")
+	l.add(s, pos+0x0C, 4, "VolumeLabelOffset")
+	if v.VolumeLabelOffset == 0x14 {
+		l.add(s, pos+0x10, 4, "VolumeLabelOffsetUnicode")
+		l.str(s, "VolumeLabelUnicode", pos+int64(v.VolumeLabelOffsetUnicode), end, true)
+		return
+	}
+	l.str(s, "VolumeLabel", pos+int64(v.VolumeLabelOffset), end, false)
+}
+
+// fields adds the structure and fields of the CommonNetworkRelativeLink.
+func (c CommonNetworkRelativeLink) fields(l *fieldLayout) {
+	const s = "LinkInfo.CommonNetworkRelativeLink"
+	pos, end := c.Span.Offset, c.Span.End()
+	l.structure(s, c.Span)
+	for i, name := range []string{"CommonNetworkRelativeLinkSize", "CommonNetworkRelativeLinkFlags",
+		"NetNameOffset", "DeviceNameOffset", "NetworkProviderType"} {
+		l.add(s, pos+int64(i*4), 4, name)
+	}
+	str := func(name string, offset uint32, unicode bool) {
+		if offset != 0 {
+			l.str(s, name, pos+int64(offset), end, unicode)
+		}
+	}
+	if c.NetNameOffset > 0x14 {
+		l.add(s, pos+0x14, 4, "NetNameOffsetUnicode")
+		l.add(s, pos+0x18, 4, "DeviceNameOffsetUnicode")
+		str("NetNameUnicode", c.NetNameOffsetUnicode, true)
+		str("DeviceNameUnicode", c.DeviceNameOffsetUnicode, true)
+	}
+	str("NetName", c.NetNameOffset, false)
+	if bitMaskuint32(c.CommonNetworkRelativeLinkFlags, 0) {
+		str("DeviceName", c.DeviceNameOffset, false)
+	}
+}
+
+// fields adds the structure and fields of the StringData. The size of each
+// string is calculated from the parsed value.
+func (st StringDataSection) fields(l *fieldLayout, linkFlags FlagMap) {
+	const s = "StringData"
+	l.structure(s, st.Span)
+	pos := st.Span.Offset
+	for _, str := range []struct {
+		flag, name, value string
+		raw               []byte
+	}{
+		{"HasName", "NameString", st.NameString, st.NameStringRaw},
+		{"HasRelativePath", "RelativePath", st.RelativePath, st.RelativePathRaw},
+		{"HasWorkingDir", "WorkingDir", st.WorkingDir, st.WorkingDirRaw},
+		{"HasArguments", "CommandLineArguments", st.CommandLineArguments, st.CommandLineArgumentsRaw},
+		{"HasIconLocation", "IconLocation", st.IconLocation, st.IconLocationRaw},
+	} {
+		if !linkFlags[str.flag] {
+			continue
+		}
+		size := int64(len(str.raw))
+		if linkFlags["IsUnicode"] {
+			size = int64(len(utf16.Encode([]rune(str.value))) * 2)
+		}
+		l.add(s, pos, 2, str.name+".CountCharacters")
+		l.add(s, pos+2, size, str.name)
+		pos += 2 + size
+	}
+}
+
+// fields adds the structures and fields of the ExtraData blocks.
+func (e ExtraDataSection) fields(l *fieldLayout) {
+	if e.Span.Size > 0 {
+		l.structure("ExtraData", e.Span)
+	}
+	for _, b := range e.Blocks {
+		s := "ExtraData." + b.Type
+		if blockSizes[b.Signature] == 0 && blockMinSizes[b.Signature] == 0 {
+			s = "ExtraData.UnknownDataBlock"
+		}
+		pos := b.Span.Offset
+		l.structure(s, b.Span)
+		l.add(s, pos, 4, "BlockSize")
+		l.add(s, pos+4, 4, "BlockSignature")
+		if fields, ok := blockFields[b.Signature]; ok && b.Parsed != nil {
+			for _, fi := range fields {
+				l.add(s, pos+8+fi.Offset, fi.Size, fi.Name)
+			}
+		} else {
+			l.add(s, pos+8, b.Span.Size-8, "Data")
+		}
+	}
+	if e.Span.Size > 0 {
+		l.add("ExtraData", e.Span.End()-4, 4, "TerminalBlock")
+	}
+}
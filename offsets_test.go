@@ -0,0 +1,62 @@
+package lnk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+)
+
+func TestOffsets(t *testing.T) {
+	data, err := ioutil.ReadFile("test/nem.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Read(bytes.NewReader(data), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]Span)
+	for _, fi := range f.Offsets() {
+		if fi.Offset < 0 || fi.End() > int64(len(data)) {
+			t.Errorf("%s is outside of the file: %+v", fi.Name, fi.Span)
+		}
+		got[fi.Name] = fi.Span
+	}
+
+	tests := []struct {
+		name string
+		want Span
+	}{
+		{"ShellLinkHeader", Span{0, 0x4C}},
+		{"ShellLinkHeader.LinkFlags", Span{0x14, 4}},
+		{"LinkTargetIDList", Span{0x4C, 0xE8}},
+		{"LinkTargetIDList.IDList.ItemID[2]", Span{0x9C, 0x96}},
+		{"LinkTargetIDList.IDList.ItemID[2].Data", Span{0x9E, 0x94}},
+		{"LinkInfo", Span{0x134, 0x66}},
+		{"LinkInfo.VolumeID", Span{0x150, 0x11}},
+		{"LinkInfo.VolumeID.DriveSerialNumber", Span{0x158, 4}},
+		{"LinkInfo.LocalBasePath", Span{0x161, 0x38}},
+		{"StringData", Span{0x19A, 0x9E}},
+		{"StringData.WorkingDir", Span{0x214, 0x24}},
+		{"ExtraData", Span{0x238, 0xA9}},
+		{"ExtraData.TrackerDataBlock", Span{0x238, 0x60}},
+		{"ExtraData.TrackerDataBlock.MachineID", Span{0x248, 0x10}},
+		{"ExtraData.TerminalBlock", Span{0x2DD, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got[tt.name] != tt.want {
+				t.Errorf("Offsets() %s = %+v, want %+v", tt.name, got[tt.name], tt.want)
+			}
+		})
+	}
+
+	// The section functions return spans from the start of their data.
+	info, err := LinkInfo(bytes.NewReader(data[0x134:]), uint64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Span{0x1C, 0x11}); info.VolID.Span != want {
+		t.Errorf("LinkInfo() VolID.Span = %+v, want %+v", info.VolID.Span, want)
+	}
+}
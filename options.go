@@ -0,0 +1,54 @@
+package lnk
+
+// Option changes how Read and File parse a file.
+type Option func(*options)
+
+// options are the parse options. The zero value is the default.
+type options struct {
+	codePage int
+	lenient  bool
+	overlay  bool
+	limits   Limits
+}
+
+// newOptions applies opts to the default options.
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithCodePage sets the Windows code page of ANSI strings, e.g. 1252 (Western
+// European), 1251 (Cyrillic), 932 (Japanese), 936 (Simplified Chinese), 949
+// (Korean) or 1250 (Central European). The default is CodePageAuto which
+// picks the code page that turns the ANSI strings into their Unicode versions
+// in the same file, or guesses it from the bytes if the file has none.
+// LnkFile.CodePage is the code page that was used.
+func WithCodePage(cp int) Option {
+	return func(o *options) {
+		o.codePage = cp
+	}
+}
+
+// Lenient parses damaged files on a best-effort basis. Errors after the
+// header are added to LnkFile.Warnings with their offset, the parser skips or
+// searches past the damaged part and returns everything it could read. Read
+// only returns an error if the header cannot be parsed. The whole file is
+// read into memory.
+func Lenient() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// WithOverlay reads the data after the ExtraData TerminalBlock, at most
+// maxSize bytes of the file, to find LnkFile.Overlay. The reader is consumed
+// to its end. Without it Read stops at the TerminalBlock. File always uses
+// it.
+func WithOverlay() Option {
+	return func(o *options) {
+		o.overlay = true
+	}
+}
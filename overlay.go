@@ -0,0 +1,196 @@
+package lnk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Overlay is the data appended after the ExtraData TerminalBlock. Windows
+// ignores it, malicious shortcuts use it to carry payloads.
+type Overlay struct {
+	// Offset from the start of the file.
+	Offset int64
+	// Size of the overlay, zero if there is none.
+	Size int64
+	// Entropy is the Shannon entropy in bits per byte, from 0 to 8.
+	// Compressed and encrypted data is close to 8.
+	Entropy float64
+	// Type is sniffed from the start of the overlay, see sniffOverlay.
+	Type string
+}
+
+// String returns the overlay in one line, "none" if there is no overlay.
+func (o Overlay) String() string {
+	if o.Size == 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%d bytes at offset 0x%X - %s, entropy %.2f", o.Size, o.Offset, o.Type, o.Entropy)
+}
+
+// sniffSize is the number of bytes used to sniff the overlay type.
+const sniffSize = 512
+
+// overlayMagics are the file signatures at the start of an overlay.
+var overlayMagics = []struct {
+	magic []byte
+	name  string
+}{
+	{[]byte("MZ"), "PE"},
+	{[]byte("PK\x03\x04"), "ZIP"},
+	{[]byte("MSCF"), "CAB"},
+	{[]byte("Rar!\x1A\x07"), "RAR"},
+	{[]byte("7z\xBC\xAF\x27\x1C"), "7Z"},
+	{[]byte("\x1F\x8B"), "GZIP"},
+	{[]byte("\xD0\xCF\x11\xE0\xA1\xB1\x1A\xE1"), "OLE"},
+	{[]byte("%PDF"), "PDF"},
+	{[]byte("{\\rtf"), "RTF"},
+	{[]byte("\x7FELF"), "ELF"},
+	{[]byte("\x89PNG"), "PNG"},
+	{[]byte("\xFF\xD8\xFF"), "JPEG"},
+	{[]byte("GIF8"), "GIF"},
+	{[]byte("L\x00\x00\x00\x01\x14\x02\x00"), "LNK"},
+}
+
+// scriptMarkers are lower case strings that make a text overlay a script.
+var scriptMarkers = []string{
+	"#!", "@echo", "powershell", "cmd /c", "cmd.exe", "wscript", "cscript",
+	"createobject", "mshta", "<script", "<hta:", "<html", "function ",
+}
+
+// sniffOverlay returns the type of the overlay from its first bytes and
+// whether the whole overlay is zero:
+//
+//   - The name of the format if it starts with a known signature, e.g. PE,
+//     ZIP, CAB, RAR, 7Z, GZIP, OLE, PDF, RTF or LNK.
+//   - Script if it's text with a marker such as powershell or <script.
+//   - Text for other UTF-8 text and UTF-16 Text for UTF-16LE text.
+//   - Zero if all bytes are zero, usually padding.
+//   - Data otherwise.
+func sniffOverlay(head []byte, zero bool) string {
+	if zero {
+		return "Zero"
+	}
+	for _, m := range overlayMagics {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.name
+		}
+	}
+	if isUTF16Text(head) {
+		if isScript(readUnicodeString(head)) {
+			return "Script"
+		}
+		return "UTF-16 Text"
+	}
+	text := string(head)
+	if len(head) == sniffSize {
+		// The last rune can be cut.
+		for i := 0; i < utf8.UTFMax-1 && !utf8.ValidString(text); i++ {
+			text = text[:len(text)-1]
+		}
+	}
+	if !isText(text) {
+		return "Data"
+	}
+	if isScript(text) {
+		return "Script"
+	}
+	return "Text"
+}
+
+// isScript returns true if the text has one of the scriptMarkers.
+func isScript(text string) bool {
+	lower := strings.ToLower(text)
+	for _, m := range scriptMarkers {
+		if strings.Contains(lower, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isText returns true if s is valid UTF-8 without control characters other
+// than whitespace.
+func isText(s string) bool {
+	if !utf8.ValidString(s) {
+		return false
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUTF16Text returns true if b looks like UTF-16LE ASCII text, with or
+// without a byte order mark.
+func isUTF16Text(b []byte) bool {
+	b = bytes.TrimPrefix(b, []byte{0xFF, 0xFE})
+	if len(b) < 4 {
+		return false
+	}
+	for i := 0; i+1 < len(b); i += 2 {
+		if b[i+1] != 0 || (b[i] < 0x20 && b[i] != '\t' && b[i] != '\n' && b[i] != '\r') {
+			return false
+		}
+	}
+	return true
+}
+
+// overlayScanner calculates the overlay details from its data.
+type overlayScanner struct {
+	counts [256]int64
+	head   []byte
+	size   int64
+}
+
+// Write adds data to the overlay.
+func (s *overlayScanner) Write(p []byte) (int, error) {
+	if n := sniffSize - len(s.head); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		s.head = append(s.head, p[:n]...)
+	}
+	for _, b := range p {
+		s.counts[b]++
+	}
+	s.size += int64(len(p))
+	return len(p), nil
+}
+
+// overlay returns the overlay at offset.
+func (s *overlayScanner) overlay(offset int64) Overlay {
+	if s.size == 0 {
+		return Overlay{}
+	}
+	o := Overlay{Offset: offset, Size: s.size}
+	for _, c := range s.counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(s.size)
+		o.Entropy -= p * math.Log2(p)
+	}
+	// -0 for a single byte value.
+	o.Entropy = math.Abs(o.Entropy)
+	o.Type = sniffOverlay(s.head, s.counts[0] == s.size)
+	return o
+}
+
+// readOverlay reads the rest of r, at most max bytes, and returns the overlay
+// at offset. The data is not kept.
+func readOverlay(r io.Reader, offset int64, max uint64) (Overlay, error) {
+	if max > math.MaxInt64 {
+		max = math.MaxInt64
+	}
+	var s overlayScanner
+	if _, err := io.Copy(&s, io.LimitReader(r, int64(max))); err != nil {
+		return Overlay{}, err
+	}
+	return s.overlay(offset), nil
+}
@@ -0,0 +1,88 @@
+package lnk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"testing"
+)
+
+func TestOverlay(t *testing.T) {
+	data, err := ioutil.ReadFile("test/nem.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	end := int64(len(data))
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	tests := []struct {
+		name        string
+		payload     []byte
+		wantType    string
+		minEntropy  float64
+		maxEntropy  float64
+		wantFinding bool
+	}{
+		{"none", nil, "", 0, 0, false},
+		{"pe", append([]byte("MZ\x90\x00"), random...), "PE", 7.5, 8, true},
+		{"cab", []byte("MSCF\x00\x00\x00\x00"), "CAB", 0, 8, true},
+		{"script", []byte("@echo off\r\npowershell -enc AAAA\r\n"), "Script", 0, 8, true},
+		{"utf16-script", []byte("\xFF\xFEc\x00m\x00d\x00.\x00e\x00x\x00e\x00"), "Script", 0, 8, true},
+		{"text", []byte("hello world"), "Text", 0, 8, true},
+		{"zero", make([]byte, 100), "Zero", 0, 0, true},
+		{"data", random, "Data", 7.5, 8, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := append(data[:len(data):len(data)], tt.payload...)
+			for _, lenient := range []bool{false, true} {
+				opts := []Option{WithOverlay()}
+				if lenient {
+					opts = append(opts, Lenient())
+				}
+				f, err := Read(bytes.NewReader(file), uint64(len(file)), opts...)
+				if err != nil {
+					t.Fatalf("Read(lenient: %t) error = %v", lenient, err)
+				}
+				o := f.Overlay
+				if o.Size != int64(len(tt.payload)) || o.Type != tt.wantType {
+					t.Errorf("Read(lenient: %t) Overlay = %+v, want %d bytes of %s", lenient, o, len(tt.payload), tt.wantType)
+				}
+				if o.Size > 0 && o.Offset != end {
+					t.Errorf("Read(lenient: %t) Overlay.Offset = 0x%X, want 0x%X", lenient, o.Offset, end)
+				}
+				if o.Entropy < tt.minEntropy || o.Entropy > tt.maxEntropy {
+					t.Errorf("Read(lenient: %t) Overlay.Entropy = %.2f, want %.2f to %.2f", lenient, o.Entropy, tt.minEntropy, tt.maxEntropy)
+				}
+
+				found := false
+				for _, fi := range f.Validate() {
+					found = found || fi.Rule == "LNK507"
+				}
+				if found != tt.wantFinding {
+					t.Errorf("Validate() LNK507 = %t, want %t", found, tt.wantFinding)
+				}
+			}
+		})
+	}
+
+	// Bytes after maxSize are not read.
+	file := append(data[:len(data):len(data)], "payload"...)
+	f, err := Read(bytes.NewReader(file), uint64(len(file)-2), WithOverlay())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Overlay.Size != 5 {
+		t.Errorf("Read() Overlay.Size = %d, want 5", f.Overlay.Size)
+	}
+
+	// Without WithOverlay, Read stops at the TerminalBlock.
+	r := bytes.NewReader(file)
+	if f, err = Read(r, uint64(len(file))); err != nil {
+		t.Fatal(err)
+	}
+	if f.Overlay.Size != 0 || r.Len() != len("payload") {
+		t.Errorf("Read() Overlay = %+v with %d bytes left, want none and 7 bytes", f.Overlay, r.Len())
+	}
+}
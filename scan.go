@@ -0,0 +1,273 @@
+package lnk
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// SymlinkPolicy decides what Scan does with symbolic links.
+type SymlinkPolicy int
+
+const (
+	// SymlinkSkip ignores all symbolic links. This is the default.
+	SymlinkSkip SymlinkPolicy = iota
+	// SymlinkFollowFiles parses links to files but does not enter links to
+	// directories.
+	SymlinkFollowFiles
+	// SymlinkFollowAll parses links to files and enters links to directories.
+	// Each directory is only visited once to avoid loops.
+	SymlinkFollowAll
+)
+
+// ScanOptions configures Scan. The zero value is valid.
+type ScanOptions struct {
+	// Workers is the number of files parsed at the same time. Defaults to
+	// runtime.NumCPU().
+	Workers int
+	// Extensions are the file extensions to parse, e.g. ".lnk". Case
+	// insensitive. Defaults to ".lnk". Use "*" to parse every file.
+	Extensions []string
+	// Symlinks is the symbolic link policy. Defaults to SymlinkSkip.
+	Symlinks SymlinkPolicy
+	// Progress is called after each file is parsed. Calls are not concurrent.
+	Progress func(ScanProgress)
+	// ReadOptions are passed to Read for each file.
+	ReadOptions []Option
+}
+
+// ScanProgress is passed to ScanOptions.Progress.
+type ScanProgress struct {
+	// Path of the last file.
+	Path string
+	// Found is the number of matching files and unreadable paths found so
+	// far. It increases while the directory tree is walked.
+	Found int
+	// Done is the number of results, including errors.
+	Done int
+	// Errors is the number of results with an error.
+	Errors int
+}
+
+// ScanResult is the result of parsing one file.
+type ScanResult struct {
+	Path string
+	// SHA256 is the hex encoded SHA-256 hash of the file. Empty if the file
+	// could not be read.
+	SHA256 string
+	File   LnkFile
+	// Err is not nil if the file or directory could not be read or the file
+	// could not be parsed.
+	Err error
+}
+
+// Scan walks the directory tree at root and parses matching files with a pool
+// of workers. Results are sent to the returned channel in no particular order
+// and the channel is closed when all files are processed or ctx is canceled.
+// Errors reading directories are also returned as results. root can be a
+// file.
+func Scan(ctx context.Context, root string, opts ScanOptions) <-chan ScanResult {
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	paths := make(chan string, workers)
+	results := make(chan ScanResult, workers)
+	s := &scanner{ctx: ctx, opts: opts, paths: paths, results: results, visited: map[string]bool{}}
+
+	go func() {
+		defer close(paths)
+		s.walk(root, true)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				// Drain the remaining paths without parsing them.
+				if ctx.Err() != nil {
+					continue
+				}
+				s.send(s.parse(path))
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	return results
+}
+
+// scanner contains the state of one Scan.
+type scanner struct {
+	ctx     context.Context
+	opts    ScanOptions
+	paths   chan<- string
+	results chan<- ScanResult
+
+	// Only used by the walker goroutine.
+	visited map[string]bool
+
+	// Protects progress and serializes the Progress calls.
+	mu       sync.Mutex
+	progress ScanProgress
+}
+
+// walk sends the matching files under path to s.paths. Returns false if ctx
+// is canceled.
+func (s *scanner) walk(path string, root bool) bool {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return s.walkError(path, err)
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		if s.opts.Symlinks == SymlinkSkip && !root {
+			return true
+		}
+		// Stat follows the link.
+		if info, err = os.Stat(path); err != nil {
+			return s.walkError(path, err)
+		}
+		if info.IsDir() && s.opts.Symlinks != SymlinkFollowAll && !root {
+			return true
+		}
+	}
+
+	if !info.IsDir() {
+		if !info.Mode().IsRegular() || !s.match(path) {
+			return true
+		}
+		s.found()
+		select {
+		case s.paths <- path:
+			return true
+		case <-s.ctx.Done():
+			return false
+		}
+	}
+
+	// Do not visit the same directory twice when following links.
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		if s.visited[real] {
+			return true
+		}
+		s.visited[real] = true
+	}
+
+	entries, err := ioutil.ReadDir(path)
+	if err != nil {
+		return s.walkError(path, err)
+	}
+	for _, e := range entries {
+		if !s.walk(filepath.Join(path, e.Name()), false) {
+			return false
+		}
+	}
+	return true
+}
+
+// found increases the number of found files.
+func (s *scanner) found() {
+	s.mu.Lock()
+	s.progress.Found++
+	s.mu.Unlock()
+}
+
+// walkError sends an error for a path that could not be read.
+func (s *scanner) walkError(path string, err error) bool {
+	s.found()
+	return s.send(ScanResult{Path: path, Err: fmt.Errorf("golnk.Scan: %s", err.Error())})
+}
+
+// match returns true if the extension of path is in Extensions.
+func (s *scanner) match(path string) bool {
+	exts := s.opts.Extensions
+	if len(exts) == 0 {
+		exts = []string{".lnk"}
+	}
+	ext := filepath.Ext(path)
+	for _, e := range exts {
+		if e == "*" || strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// parse reads, hashes and parses one file. Files larger than the MaxAlloc
+// limit in ReadOptions are not read.
+func (s *scanner) parse(path string) ScanResult {
+	res := ScanResult{Path: path}
+	data, err := s.readFile(path)
+	if err != nil {
+		res.Err = fmt.Errorf("golnk.Scan: %w", err)
+		return res
+	}
+	sum := sha256.Sum256(data)
+	res.SHA256 = hex.EncodeToString(sum[:])
+	res.File, res.Err = Read(bytes.NewReader(data), uint64(len(data)), s.opts.ReadOptions...)
+	return res
+}
+
+// readFile returns the contents of path if it is not larger than MaxAlloc.
+func (s *scanner) readFile(path string) ([]byte, error) {
+	max := newBudget(newOptions(s.opts.ReadOptions).limits).limits.MaxAlloc
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() > max {
+		return nil, fmt.Errorf("%w - file size %d is more than %d bytes", ErrLimitExceeded, fi.Size(), max)
+	}
+	// The file can grow after Stat.
+	data, err := ioutil.ReadAll(io.LimitReader(file, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, fmt.Errorf("%w - file size is more than %d bytes", ErrLimitExceeded, max)
+	}
+	return data, nil
+}
+
+// send updates the progress and sends the result. Returns false if ctx is
+// canceled.
+func (s *scanner) send(res ScanResult) bool {
+	s.mu.Lock()
+	s.progress.Path = res.Path
+	s.progress.Done++
+	if res.Err != nil {
+		s.progress.Errors++
+	}
+	if s.opts.Progress != nil {
+		s.opts.Progress(s.progress)
+	}
+	s.mu.Unlock()
+
+	select {
+	case s.results <- res:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
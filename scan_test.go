@@ -0,0 +1,140 @@
+package lnk
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// scanPaths runs Scan and returns the sorted base names of the files without
+// errors and the number of errors.
+func scanPaths(t *testing.T, ctx context.Context, root string, opts ScanOptions) ([]string, int) {
+	t.Helper()
+	var names []string
+	errs := 0
+	for res := range Scan(ctx, root, opts) {
+		if res.Err != nil {
+			errs++
+			continue
+		}
+		if len(res.SHA256) != 64 {
+			t.Errorf("%s: invalid SHA256 %q", res.Path, res.SHA256)
+		}
+		names = append(names, filepath.Base(res.Path))
+	}
+	sort.Strings(names)
+	return names, errs
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ScanOptions
+		want []string
+	}{
+		{"default", ScanOptions{},
+			[]string{"Visual Studio Code.lnk", "Windows Store.lnk", "test-orig.lnk", "test.lnk", "vbox-svr-win10.lnk"}},
+		{"extensions", ScanOptions{Workers: 1, Extensions: []string{".TEST"}},
+			[]string{"nem.test", "remote.directory.xp.test", "remote.file.xp.test"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := scanPaths(t, context.Background(), "test", tt.opts)
+			if errs != 0 {
+				t.Errorf("Scan() returned %d errors", errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanLimit(t *testing.T) {
+	opts := ScanOptions{ReadOptions: []Option{WithLimits(Limits{MaxAlloc: 16})}}
+	n := 0
+	for res := range Scan(context.Background(), "test", opts) {
+		n++
+		if !errors.Is(res.Err, ErrLimitExceeded) {
+			t.Errorf("%s: Scan() error = %v, want ErrLimitExceeded", res.Path, res.Err)
+		}
+	}
+	if n != 5 {
+		t.Errorf("Scan() returned %d results, want 5", n)
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	var last ScanProgress
+	opts := ScanOptions{
+		Extensions: []string{"*"},
+		Progress:   func(p ScanProgress) { last = p },
+	}
+	// Every file in test is parsed, the go files are errors.
+	_, errs := scanPaths(t, context.Background(), "test", opts)
+	if last.Done != last.Found || last.Errors != errs || errs == 0 {
+		t.Errorf("last progress = %+v, got %d errors", last, errs)
+	}
+}
+
+func TestScanCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	got, _ := scanPaths(t, ctx, "test", ScanOptions{})
+	// Some files may be sent before the workers see the cancellation.
+	if len(got) > 5 {
+		t.Errorf("Scan() after cancel = %v", got)
+	}
+}
+
+func TestScanSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "golnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	abs, err := filepath.Abs("test/test.lnk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// dir/sub/link.lnk -> test/test.lnk
+	// dir/loop -> dir
+	if err := os.Symlink(abs, filepath.Join(sub, "link.lnk")); err != nil {
+		t.Skipf("symlinks not supported - %v", err)
+	}
+	if err := os.Symlink(dir, filepath.Join(dir, "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		policy SymlinkPolicy
+		want   []string
+	}{
+		{"skip", SymlinkSkip, nil},
+		{"files", SymlinkFollowFiles, []string{"link.lnk"}},
+		// The loop link is not followed because dir was already visited.
+		{"all", SymlinkFollowAll, []string{"link.lnk"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := scanPaths(t, context.Background(), dir, ScanOptions{Symlinks: tt.policy})
+			if errs != 0 {
+				t.Errorf("Scan() returned %d errors", errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Scan() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,359 @@
+package lnk
+
+import (
+	"bytes"
+	"strings"
+)
+
+/*
+	Shell items are the ItemIDs in the LinkTargetIDList. Their format is not in
+	[MS-SHLLINK], it depends on the namespace extension that created them.
+	The first byte after the size is the class type indicator that is used to
+	identify the item:
+
+	0x1F       - Root folder (e.g. My Computer) with a shell folder GUID.
+	0x20..0x2F - Volume (e.g. C:\).
+	0x30..0x3F - File entry (file or directory).
+	0x40..0x4F - Network location. 0x80 may also be set (e.g. 0xC3).
+	0x52       - Compressed folder.
+	0x61       - URI.
+	0x71       - Control panel item.
+	0x74       - Delegate item ("CFSF" signature) that wraps a file entry.
+
+	Offsets in this file are from the start of ItemID.Data, which is one byte
+	after the ItemID size (the class type indicator is Data[0]).
+*/
+
+// ShellItem is a typed ItemID.
+type ShellItem interface {
+	// ClassType returns the class type indicator of the item.
+	ClassType() byte
+	// TypeName returns the name of the shell item type, e.g. "File Entry".
+	TypeName() string
+	// Name returns the name of the item used when building paths, e.g. "C:\"
+	// or the file name. It's empty if the item has no name.
+	Name() string
+}
+
+// RootFolderItem is a shell folder such as My Computer or the Control Panel.
+type RootFolderItem struct {
+	Class     byte
+	SortIndex byte
+	// FolderID is the CLSID of the shell folder.
+	FolderID GUID
+}
+
+// ClassType returns the class type indicator.
+func (r RootFolderItem) ClassType() byte { return r.Class }
+
+// TypeName returns "Root Folder".
+func (r RootFolderItem) TypeName() string { return "Root Folder" }
+
+// Name returns the name of the shell folder (e.g. My Computer) or its CLSID.
+func (r RootFolderItem) Name() string { return folderName(r.FolderID) }
+
+// VolumeItem is a drive. Class 0x2F has a drive letter (e.g. C:\), class 0x2E
+// has a shell folder GUID instead.
+type VolumeItem struct {
+	Class    byte
+	Drive    string
+	FolderID GUID
+}
+
+// ClassType returns the class type indicator.
+func (v VolumeItem) ClassType() byte { return v.Class }
+
+// TypeName returns "Volume".
+func (v VolumeItem) TypeName() string { return "Volume" }
+
+// Name returns the drive letter or the name of the shell folder.
+func (v VolumeItem) Name() string {
+	if v.Drive != "" {
+		return v.Drive
+	}
+	return folderName(v.FolderID)
+}
+
+// NetworkItem is a network location such as a domain, server or share.
+type NetworkItem struct {
+	Class byte
+	Flags byte
+	// Location is a UNC path (e.g. \\server\share) or a network name.
+	Location    string
+	Description string // Present if Flags has 0x80.
+	Comments    string // Present if Flags has 0x40.
+}
+
+// ClassType returns the class type indicator.
+func (n NetworkItem) ClassType() byte { return n.Class }
+
+// TypeName returns "Network Location".
+func (n NetworkItem) TypeName() string { return "Network Location" }
+
+// Name returns the location.
+func (n NetworkItem) Name() string { return n.Location }
+
+// CompressedFolderItem is an item inside a zip file.
+type CompressedFolderItem struct {
+	Class byte
+}
+
+// ClassType returns the class type indicator.
+func (c CompressedFolderItem) ClassType() byte { return c.Class }
+
+// TypeName returns "Compressed Folder".
+func (c CompressedFolderItem) TypeName() string { return "Compressed Folder" }
+
+// Name is not decoded.
+func (c CompressedFolderItem) Name() string { return "" }
+
+// URIItem is a URI such as http://example.com or ftp://server.
+type URIItem struct {
+	Class byte
+	Flags byte
+	URI   string
+}
+
+// ClassType returns the class type indicator.
+func (u URIItem) ClassType() byte { return u.Class }
+
+// TypeName returns "URI".
+func (u URIItem) TypeName() string { return "URI" }
+
+// Name returns the URI.
+func (u URIItem) Name() string { return u.URI }
+
+// ControlPanelItem is an item in the Control Panel.
+type ControlPanelItem struct {
+	Class byte
+	// ItemID is the CLSID of the control panel item.
+	ItemID GUID
+}
+
+// ClassType returns the class type indicator.
+func (c ControlPanelItem) ClassType() byte { return c.Class }
+
+// TypeName returns "Control Panel".
+func (c ControlPanelItem) TypeName() string { return "Control Panel" }
+
+// Name returns the name of the item or its CLSID.
+func (c ControlPanelItem) Name() string { return folderName(c.ItemID) }
+
+// DelegateItem wraps another shell item (usually a file entry) that is
+// handled by a delegate folder, e.g. the files in a user's profile.
+type DelegateItem struct {
+	Class byte
+	// Item is the wrapped shell item.
+	Item ShellItem
+	// DelegateID is usually {5E591A74-DF96-48D3-8D67-1733BCEE28BA}.
+	DelegateID GUID
+	// ItemClassID is the CLSID of the folder that handles the item.
+	ItemClassID GUID
+}
+
+// ClassType returns the class type indicator.
+func (d DelegateItem) ClassType() byte { return d.Class }
+
+// TypeName returns "Delegate".
+func (d DelegateItem) TypeName() string { return "Delegate" }
+
+// Name returns the name of the wrapped item.
+func (d DelegateItem) Name() string {
+	if d.Item == nil {
+		return ""
+	}
+	return d.Item.Name()
+}
+
+// UnknownItem is a shell item without a decoder.
+type UnknownItem struct {
+	Class byte
+	// Data is the ItemID data, including the class type indicator.
+	Data []byte
+}
+
+// ClassType returns the class type indicator.
+func (u UnknownItem) ClassType() byte { return u.Class }
+
+// TypeName returns "Unknown".
+func (u UnknownItem) TypeName() string { return "Unknown" }
+
+// Name returns an empty string.
+func (u UnknownItem) Name() string { return "" }
+
+// delegateSignature appears after the size in delegate items.
+var delegateSignature = []byte("CFSF")
+
+// ParseShellItem returns the typed shell item of an ItemID.Data. Items that
+// cannot be decoded are returned as UnknownItem. Empty data returns nil. ANSI
+// names are decoded with CodePageAuto.
+func ParseShellItem(data []byte) ShellItem {
+	return parseShellItem(data, &itemDecoder{cp: CodePageAuto})
+}
+
+// itemDecoder decodes the names of a shell item. ANSI names use the code page
+// cp. err is the first invalid UTF-16 name, the name is kept with U+FFFD.
+type itemDecoder struct {
+	cp  int
+	err error
+}
+
+// unicode decodes a UTF-16 name with decodeUTF16.
+func (dec *itemDecoder) unicode(data []byte) string {
+	str, err := decodeUTF16(data)
+	if err != nil && dec.err == nil {
+		dec.err = err
+	}
+	return str
+}
+
+// parseShellItem is ParseShellItem with the decoder of the names.
+func parseShellItem(data []byte, dec *itemDecoder) ShellItem {
+	if len(data) == 0 {
+		return nil
+	}
+	class := data[0]
+	unknown := UnknownItem{Class: class, Data: data}
+
+	switch {
+	case class == 0x1F:
+		if len(data) < 18 {
+			return unknown
+		}
+		r := RootFolderItem{Class: class, SortIndex: data[1]}
+		copy(r.FolderID[:], data[2:18])
+		return r
+
+	case class&0x70 == 0x20:
+		v := VolumeItem{Class: class}
+		if class == 0x2E {
+			if len(data) < 18 {
+				return unknown
+			}
+			copy(v.FolderID[:], data[2:18])
+			return v
+		}
+		v.Drive = readString(data[1:])
+		return v
+
+	case class&0x70 == 0x30:
+		return fileEntryItem(data, unknown, dec)
+
+	case class&0x70 == 0x40:
+		if len(data) < 3 {
+			return unknown
+		}
+		n := NetworkItem{Class: class, Flags: data[2]}
+		rest := data[3:]
+		n.Location, rest = nextString(rest, dec.cp)
+		if n.Flags&0x80 != 0 {
+			n.Description, rest = nextString(rest, dec.cp)
+		}
+		if n.Flags&0x40 != 0 {
+			n.Comments, _ = nextString(rest, dec.cp)
+		}
+		return n
+
+	case class == 0x52:
+		return CompressedFolderItem{Class: class}
+
+	case class == 0x61:
+		// Flags, then a uint16 data size, the data and the URI.
+		if len(data) < 4 {
+			return unknown
+		}
+		u := URIItem{Class: class, Flags: data[1]}
+		offset := 4 + int(uint16Little(data[2:]))
+		if offset >= len(data) {
+			return unknown
+		}
+		if u.Flags&0x80 != 0 {
+			u.URI = dec.unicode(data[offset:])
+		} else {
+			u.URI, _ = readANSI(data[offset:], dec.cp)
+		}
+		return u
+
+	case class == 0x71:
+		// The CLSID is at offset 12.
+		if len(data) < 28 {
+			return unknown
+		}
+		c := ControlPanelItem{Class: class}
+		copy(c.ItemID[:], data[12:28])
+		return c
+
+	case class == 0x74:
+		return delegateItem(data, unknown, dec)
+	}
+	return unknown
+}
+
+// delegateItem parses a delegate item:
+// class (1), unknown (1), uint16 size of the signature and wrapped item,
+// "CFSF", the wrapped item, the delegate GUID, the item class GUID and the
+// extension blocks of the wrapped item.
+func delegateItem(data []byte, unknown UnknownItem, dec *itemDecoder) ShellItem {
+	if len(data) < 10 || !bytes.Equal(data[4:8], delegateSignature) {
+		return unknown
+	}
+	size := int(uint16Little(data[2:]))
+	guidOffset := 4 + size
+	if guidOffset+32 > len(data) {
+		return unknown
+	}
+	d := DelegateItem{Class: data[0]}
+
+	// The wrapped item starts with its own uint16 size.
+	innerSize := int(uint16Little(data[8:]))
+	if innerSize > 2 && 8+innerSize <= guidOffset {
+		d.Item = parseShellItem(data[10:8+innerSize], dec)
+	}
+	copy(d.DelegateID[:], data[guidOffset:guidOffset+16])
+	copy(d.ItemClassID[:], data[guidOffset+16:guidOffset+32])
+
+	// The extension block of the wrapped file entry is after the GUIDs.
+	if fe, ok := d.Item.(FileEntryItem); ok {
+		fe.Extension = fileEntryExtension(data[guidOffset+32:], dec)
+		d.Item = fe
+	}
+	return d
+}
+
+// nextString reads a null-terminated ANSI string and returns it and the rest
+// of the []byte after the terminator.
+func nextString(data []byte, cp int) (string, []byte) {
+	str, raw := readANSI(data, cp)
+	if len(raw)+1 >= len(data) {
+		return str, nil
+	}
+	return str, data[len(raw)+1:]
+}
+
+// nextUnicodeString reads a null-terminated UTF-16 string and returns it and
+// the rest of the []byte after the terminator.
+func nextUnicodeString(data []byte, dec *itemDecoder) (string, []byte) {
+	for i := 0; i+1 < len(data); i += 2 {
+		if data[i] == 0x00 && data[i+1] == 0x00 {
+			return dec.unicode(data[:i]), data[i+2:]
+		}
+	}
+	return dec.unicode(data), nil
+}
+
+// shellItemStr returns the type and name of a shell item for printing.
+func shellItemStr(it ShellItem) string {
+	if it == nil {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(it.TypeName())
+	sb.WriteString(" (")
+	sb.WriteString(uint32StrHex(uint32(it.ClassType())))
+	sb.WriteString(")")
+	if it.Name() != "" {
+		sb.WriteString(": ")
+		sb.WriteString(it.Name())
+	}
+	return sb.String()
+}
@@ -0,0 +1,200 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"time"
+)
+
+/*
+	File entry shell item (class type 0x30 to 0x3F). Offsets from the start of
+	ItemID.Data:
+
+	0x00 - Class type indicator. 0x01 is directory, 0x02 is file and 0x04 means
+	       the primary name is Unicode.
+	0x01 - Unknown.
+	0x02 - File size (uint32). Zero for directories.
+	0x06 - Last modification time (MS-DOS date and time).
+	0x0A - File attributes (uint16).
+	0x0C - Primary name (usually the 8.3 name), null-terminated and padded to a
+	       two-byte boundary.
+
+	Extension blocks follow the primary name. The first one is BEEF0004 with
+	the long name and extra timestamps.
+*/
+
+// FileEntryItem is a file or directory.
+type FileEntryItem struct {
+	Class byte
+	// FileSize is zero for directories and files larger than 4 GB.
+	FileSize uint32
+	// ModifiedTime is the last modification time in MS-DOS format which has no
+	// timezone and a two-second resolution.
+	ModifiedTime time.Time
+	// FileAttributes uses the same flags as the header.
+	FileAttributes FlagMap
+	// PrimaryName is usually the 8.3 name.
+	PrimaryName string
+	// Extension is the BEEF0004 extension block, nil if it does not exist.
+	Extension *FileEntryExtension
+}
+
+// ClassType returns the class type indicator.
+func (f FileEntryItem) ClassType() byte { return f.Class }
+
+// TypeName returns "File Entry".
+func (f FileEntryItem) TypeName() string { return "File Entry" }
+
+// Name returns the long name from the extension block or the primary name.
+func (f FileEntryItem) Name() string {
+	if f.Extension != nil && f.Extension.LongName != "" {
+		return f.Extension.LongName
+	}
+	return f.PrimaryName
+}
+
+// IsDirectory returns true if the directory bit (0x01) is set in the class
+// type indicator.
+func (f FileEntryItem) IsDirectory() bool { return f.Class&0x01 != 0 }
+
+// IsFile returns true if the file bit (0x02) is set in the class type
+// indicator.
+func (f FileEntryItem) IsFile() bool { return f.Class&0x02 != 0 }
+
+/*
+	BEEF0004 extension block. Offsets from the start of the block, fields
+	depend on the version:
+
+	0x00 - Size (uint16).
+	0x02 - Version (uint16). 3 (XP), 7 (Vista), 8 (Windows 7), 9 (Windows 8+).
+	0x04 - Signature 0xBEEF0004.
+	0x08 - Creation time (MS-DOS date and time).
+	0x0C - Last access time (MS-DOS date and time).
+	0x10 - Identifier (uint16). 0x14 (XP), 0x26 (Vista), 0x2E (Windows 7+).
+
+	Version 7+:
+	0x12 - Unknown (uint16).
+	0x14 - NTFS file reference: MFT entry (6 bytes) and sequence (uint16).
+	0x1C - Unknown (8 bytes).
+
+	Version 3+: Size of the localized name (uint16).
+	Version 9+: Unknown (4 bytes).
+	Version 8+: Unknown (4 bytes).
+
+	Long name (null-terminated Unicode).
+	Localized name if its size is not zero. ANSI in version 3 and Unicode in
+	version 7+.
+	Offset of the block from the start of the ItemID (uint16).
+*/
+
+// FileEntryExtension is the BEEF0004 extension block of a file entry.
+type FileEntryExtension struct {
+	Size    uint16
+	Version uint16
+	// Signature is 0xBEEF0004.
+	Signature uint32
+	// CreationTime and AccessTime are MS-DOS date and time.
+	CreationTime time.Time
+	AccessTime   time.Time
+	Identifier   uint16
+	// MFTEntry and MFTSequence are the NTFS file reference of the target.
+	// Zero for version 3 and non-NTFS volumes.
+	MFTEntry    uint64
+	MFTSequence uint16
+	// LongName is the Unicode name of the file.
+	LongName string
+	// LocalizedName is the display name, e.g. from desktop.ini.
+	LocalizedName string
+}
+
+// fileEntryExtensionSignature is the signature of FileEntryExtension.
+const fileEntryExtensionSignature = 0xBEEF0004
+
+// fileEntryItem parses a file entry shell item.
+func fileEntryItem(data []byte, unknown UnknownItem, dec *itemDecoder) ShellItem {
+	if len(data) < 12 {
+		return unknown
+	}
+	f := FileEntryItem{
+		Class:          data[0],
+		FileSize:       binary.LittleEndian.Uint32(data[2:6]),
+		ModifiedTime:   dosTime(data[6:10]),
+		FileAttributes: matchFlag(uint32(binary.LittleEndian.Uint16(data[10:12])), fileAttributesFlags),
+	}
+
+	var rest []byte
+	if f.Class&0x04 != 0 {
+		f.PrimaryName, rest = nextUnicodeString(data[12:], dec)
+	} else {
+		f.PrimaryName, rest = nextString(data[12:], dec.cp)
+		// ANSI names are padded to a two-byte boundary.
+		if (len(data)-len(rest))%2 != 0 && len(rest) > 0 {
+			rest = rest[1:]
+		}
+	}
+	f.Extension = fileEntryExtension(rest, dec)
+	return f
+}
+
+// fileEntryExtension parses the BEEF0004 extension block at the start of data.
+// Returns nil if data does not start with one. dec decodes the names.
+func fileEntryExtension(data []byte, dec *itemDecoder) *FileEntryExtension {
+	if len(data) < 18 {
+		return nil
+	}
+	e := FileEntryExtension{
+		Size:      binary.LittleEndian.Uint16(data[0:2]),
+		Version:   binary.LittleEndian.Uint16(data[2:4]),
+		Signature: binary.LittleEndian.Uint32(data[4:8]),
+	}
+	if e.Signature != fileEntryExtensionSignature || int(e.Size) > len(data) || e.Size < 18 {
+		return nil
+	}
+	// Ignore everything after the block.
+	data = data[:e.Size]
+
+	e.CreationTime = dosTime(data[8:12])
+	e.AccessTime = dosTime(data[12:16])
+	e.Identifier = binary.LittleEndian.Uint16(data[16:18])
+	offset := 18
+
+	if e.Version >= 7 {
+		if len(data) < offset+18 {
+			return &e
+		}
+		ref := binary.LittleEndian.Uint64(data[offset+2 : offset+10])
+		e.MFTEntry = ref & 0xFFFFFFFFFFFF
+		e.MFTSequence = uint16(ref >> 48)
+		offset += 18
+	}
+
+	var localizedSize uint16
+	if e.Version >= 3 {
+		if len(data) < offset+2 {
+			return &e
+		}
+		localizedSize = binary.LittleEndian.Uint16(data[offset:])
+		offset += 2
+	}
+	if e.Version >= 9 {
+		offset += 4
+	}
+	if e.Version >= 8 {
+		offset += 4
+	}
+	if offset+2 > len(data) {
+		return &e
+	}
+
+	// Remove the offset of the block at the end.
+	names := data[offset : len(data)-2]
+	var rest []byte
+	e.LongName, rest = nextUnicodeString(names, dec)
+	if localizedSize > 0 && len(rest) > 0 {
+		if e.Version >= 7 {
+			e.LocalizedName, _ = nextUnicodeString(rest, dec)
+		} else {
+			e.LocalizedName, _ = nextString(rest, dec.cp)
+		}
+	}
+	return &e
+}
@@ -0,0 +1,147 @@
+package lnk
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseShellItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		want     []string // TypeName of each item.
+		index    int      // Item to compare with item.
+		item     ShellItem
+	}{
+		{"nem", "test/nem.test",
+			[]string{"Root Folder", "Volume", "File Entry"},
+			1, VolumeItem{Class: 0x2E, FolderID: mustGUID(t, "{B4BFCC3A-DB2C-424C-B029-7FE99A87C641}")},
+		},
+		{"vscode", "test/Visual Studio Code.lnk",
+			[]string{"Root Folder", "Delegate", "File Entry", "File Entry", "File Entry", "File Entry"},
+			0, RootFolderItem{Class: 0x1F, SortIndex: 0x44, FolderID: mustGUID(t, "{59031A47-3F72-44A7-89C5-5595FE6B30EE}")},
+		},
+		{"remote-xp", "test/remote.directory.xp.test",
+			[]string{"Root Folder", "Network Location", "Network Location", "Network Location",
+				"Network Location", "Network Location", "File Entry"},
+			4, NetworkItem{Class: 0x42, Flags: 0xC2, Location: `\\als-fichiers3`,
+				Description: "Microsoft Network", Comments: "DC 2003 + CG + DNS + Wins + Serveur de fichier"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := File(tt.filename)
+			if err != nil {
+				t.Fatalf("File() error = %v", err)
+			}
+			var got []string
+			for _, it := range f.IDList.List.ItemIDList {
+				got = append(got, it.Item.TypeName())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("item types = %v, want %v", got, tt.want)
+			}
+			if it := f.IDList.List.ItemIDList[tt.index].Item; !reflect.DeepEqual(it, tt.item) {
+				t.Errorf("item %d = %#v, want %#v", tt.index, it, tt.item)
+			}
+		})
+	}
+}
+
+func TestParseShellItemDelegate(t *testing.T) {
+	f, err := File("test/Visual Studio Code.lnk")
+	if err != nil {
+		t.Fatalf("File() error = %v", err)
+	}
+	d, ok := f.IDList.List.ItemIDList[1].Item.(DelegateItem)
+	if !ok {
+		t.Fatalf("item 1 is not a DelegateItem")
+	}
+	if want := mustGUID(t, "{5E591A74-DF96-48D3-8D67-1733BCEE28BA}"); d.DelegateID != want {
+		t.Errorf("DelegateID = %v, want %v", d.DelegateID, want)
+	}
+	fe, ok := d.Item.(FileEntryItem)
+	if !ok {
+		t.Fatalf("wrapped item = %#v, want FileEntryItem", d.Item)
+	}
+	// The extension block is after the GUIDs of the delegate item.
+	if fe.Extension == nil || fe.Extension.MFTEntry != 129419 {
+		t.Errorf("wrapped item Extension = %+v, want MFTEntry 129419", fe.Extension)
+	}
+	if d.Name() != "AppData" {
+		t.Errorf("Name() = %v, want AppData", d.Name())
+	}
+}
+
+func TestParseShellItemUnknown(t *testing.T) {
+	data := []byte{0x99, 0x01, 0x02}
+	want := UnknownItem{Class: 0x99, Data: data}
+	if got := ParseShellItem(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseShellItem() = %#v, want %#v", got, want)
+	}
+	if got := ParseShellItem(nil); got != nil {
+		t.Errorf("ParseShellItem(nil) = %#v, want nil", got)
+	}
+}
+
+func TestFileEntryItem(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		index    int
+		want     FileEntryItem
+	}{
+		{"nem-v9", "test/nem.test", 2, FileEntryItem{
+			Class:          0x32,
+			FileSize:       7225,
+			ModifiedTime:   time.Date(2016, 1, 28, 15, 38, 0, 0, time.UTC),
+			FileAttributes: FlagMap{"FILE_ATTRIBUTE_ARCHIVE": true},
+			PrimaryName:    "2016-0~1.XLS",
+			Extension: &FileEntryExtension{
+				Size:         122,
+				Version:      9,
+				Signature:    fileEntryExtensionSignature,
+				CreationTime: time.Date(2016, 1, 28, 15, 38, 0, 0, time.UTC),
+				AccessTime:   time.Date(2016, 1, 28, 15, 38, 0, 0, time.UTC),
+				Identifier:   0x2E,
+				MFTEntry:     93014,
+				MFTSequence:  22,
+				LongName:     "2016-01-28-083758-HasherResults.xlsx",
+			},
+		}},
+		{"xp-v3", "test/remote.directory.xp.test", 6, FileEntryItem{
+			Class:          0x31,
+			ModifiedTime:   time.Date(2010, 7, 8, 12, 36, 2, 0, time.UTC),
+			FileAttributes: FlagMap{"FILE_ATTRIBUTE_DIRECTORY": true},
+			PrimaryName:    "GMALDH~1",
+			Extension: &FileEntryExtension{
+				Size:         44,
+				Version:      3,
+				Signature:    fileEntryExtensionSignature,
+				CreationTime: time.Date(2009, 10, 8, 13, 48, 56, 0, time.UTC),
+				AccessTime:   time.Date(2010, 7, 9, 13, 51, 22, 0, time.UTC),
+				Identifier:   0x14,
+				LongName:     "GMAldheris",
+			},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := File(tt.filename)
+			if err != nil {
+				t.Fatalf("File() error = %v", err)
+			}
+			got, ok := f.IDList.List.ItemIDList[tt.index].Item.(FileEntryItem)
+			if !ok {
+				t.Fatalf("item %d is not a FileEntryItem", tt.index)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("item = %+v, want %+v", got, tt.want)
+			}
+			if got.Extension != nil && !reflect.DeepEqual(*got.Extension, *tt.want.Extension) {
+				t.Errorf("Extension = %+v, want %+v", *got.Extension, *tt.want.Extension)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package lnk
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"io"
 	"strings"
 
@@ -39,25 +42,48 @@ type StringDataSection struct {
 	// IconLocation specifies the location of the icon to be used.
 	// Present with HasIconLocation flag.
 	IconLocation string
+
+	// The strings before decoding with the code page. Only set if IsUnicode
+	// is not set.
+	NameStringRaw           []byte
+	RelativePathRaw         []byte
+	WorkingDirRaw           []byte
+	CommandLineArgumentsRaw []byte
+	IconLocationRaw         []byte
+
+	// Span is the location of the section.
+	Span Span
+
+	// Code page of the ANSI strings, used to write them back.
+	codePage int
 }
 
 // StringData parses the StringData portion of the lnk.
-// flags is the ShellLinkHeader.LinkFlags.
+// flags is the ShellLinkHeader.LinkFlags. Errors are *ParseError.
+// DefaultLimits are used.
 func StringData(r io.Reader, linkFlags FlagMap) (st StringDataSection, err error) {
+	return stringData(r, linkFlags, newBudget(DefaultLimits))
+}
+
+// stringData parses the StringData within the limits in b.
+func stringData(r io.Reader, linkFlags FlagMap, b *budget) (st StringDataSection, err error) {
+	// Count the bytes to find the offset of each string.
+	cr := &countingReader{r: r}
 
 	// Read unicode strings if is unicode flag is set.
 	isUnicode := linkFlags["IsUnicode"]
 
 	// Read NameString if HasName flag is set.
 	if linkFlags["HasName"] {
-		name, _ := readStringData(r, isUnicode)
-		// fmt.Println("Name", name)
-		st.NameString = name
+		st.NameString, st.NameStringRaw, err = stringDataString(cr, "NameString", isUnicode, b)
+		if err != nil {
+			return st, err
+		}
 	}
 
 	// Read NameString if HasName flag is set.
 	if linkFlags["HasRelativePath"] {
-		st.RelativePath, err = readStringData(r, isUnicode)
+		st.RelativePath, st.RelativePathRaw, err = stringDataString(cr, "RelativePath", isUnicode, b)
 		if err != nil {
 			return st, err
 		}
@@ -65,7 +91,7 @@ func StringData(r io.Reader, linkFlags FlagMap) (st StringDataSection, err error
 
 	// Read WorkingDir if HasWorkingDir flag is set.
 	if linkFlags["HasWorkingDir"] {
-		st.WorkingDir, err = readStringData(r, isUnicode)
+		st.WorkingDir, st.WorkingDirRaw, err = stringDataString(cr, "WorkingDir", isUnicode, b)
 		if err != nil {
 			return st, err
 		}
@@ -73,7 +99,7 @@ func StringData(r io.Reader, linkFlags FlagMap) (st StringDataSection, err error
 
 	// Read CommandLineArguments if HasArguments flag is set.
 	if linkFlags["HasArguments"] {
-		st.CommandLineArguments, err = readStringData(r, isUnicode)
+		st.CommandLineArguments, st.CommandLineArgumentsRaw, err = stringDataString(cr, "CommandLineArguments", isUnicode, b)
 		if err != nil {
 			return st, err
 		}
@@ -81,14 +107,91 @@ func StringData(r io.Reader, linkFlags FlagMap) (st StringDataSection, err error
 
 	// Read IconLocation if HasIconLocation flag is set.
 	if linkFlags["HasIconLocation"] {
-		st.IconLocation, err = readStringData(r, isUnicode)
+		st.IconLocation, st.IconLocationRaw, err = stringDataString(cr, "IconLocation", isUnicode, b)
 		if err != nil {
 			return st, err
 		}
 	}
+	st.Span = Span{Size: cr.n}
 	return st, err
 }
 
+// stringDataString reads the string of a field with readStringData. ANSI
+// strings are decoded with CodePageAuto and their bytes are returned in raw.
+// Invalid UTF-16 is added to the warnings in b. Errors are *ParseError.
+func stringDataString(cr *countingReader, field string, isUnicode bool, b *budget) (str string, raw []byte, err error) {
+	offset := cr.n
+	str, err = readStringData(cr, isUnicode, b)
+	if errors.Is(err, ErrBadString) {
+		b.warn("StringData", field, offset, err)
+		err = nil
+	}
+	if err != nil {
+		return str, nil, parseError("StringData", field, offset, err)
+	}
+	if isUnicode {
+		return str, nil, nil
+	}
+	raw = []byte(str)
+	return decodeANSI(raw, CodePageAuto), raw, nil
+}
+
+// setCodePage decodes the ANSI strings again with the code page.
+func (st *StringDataSection) setCodePage(cp int) {
+	st.codePage = cp
+	for _, f := range []struct {
+		str *string
+		raw []byte
+	}{
+		{&st.NameString, st.NameStringRaw},
+		{&st.RelativePath, st.RelativePathRaw},
+		{&st.WorkingDir, st.WorkingDirRaw},
+		{&st.CommandLineArguments, st.CommandLineArgumentsRaw},
+		{&st.IconLocation, st.IconLocationRaw},
+	} {
+		if f.raw != nil {
+			*f.str = decodeANSI(f.raw, cp)
+		}
+	}
+}
+
+// Marshal is the reverse of StringData and returns the StringData section as
+// it appears on disk. linkFlags is the ShellLinkHeader.LinkFlags and decides
+// which strings are written and if they are Unicode.
+func (st StringDataSection) Marshal(linkFlags FlagMap) ([]byte, error) {
+	isUnicode := linkFlags["IsUnicode"]
+
+	// Same order as StringData.
+	fields := []struct {
+		flag string
+		str  string
+		raw  []byte
+	}{
+		{"HasName", st.NameString, st.NameStringRaw},
+		{"HasRelativePath", st.RelativePath, st.RelativePathRaw},
+		{"HasWorkingDir", st.WorkingDir, st.WorkingDirRaw},
+		{"HasArguments", st.CommandLineArguments, st.CommandLineArgumentsRaw},
+		{"HasIconLocation", st.IconLocation, st.IconLocationRaw},
+	}
+
+	var buf bytes.Buffer
+	for _, f := range fields {
+		if !linkFlags[f.flag] {
+			continue
+		}
+		str := f.str
+		if !isUnicode {
+			str = string(ansiBytes(f.str, f.raw, st.codePage))
+		}
+		b, err := stringDataBytes(str, isUnicode)
+		if err != nil {
+			return nil, fmt.Errorf("lnk.StringDataSection.Marshal: %s - %w", f.flag, err)
+		}
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
 // String prints StringDataSection in a table.
 func (st StringDataSection) String() string {
 	var sb strings.Builder
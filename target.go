@@ -0,0 +1,163 @@
+package lnk
+
+import "strings"
+
+// TargetSource is the section or block that a target path comes from.
+type TargetSource string
+
+// Sources of the target path.
+const (
+	SourceNone                 TargetSource = ""
+	SourceEnvironment          TargetSource = "EnvironmentVariableDataBlock"
+	SourceLocalBasePath        TargetSource = "LinkInfo.LocalBasePath"
+	SourceLocalBasePathUnicode TargetSource = "LinkInfo.LocalBasePathUnicode"
+	SourceNetName              TargetSource = "LinkInfo.NetName"
+	SourceNetNameUnicode       TargetSource = "LinkInfo.NetNameUnicode"
+	SourceKnownFolder          TargetSource = "KnownFolderDataBlock"
+	SourceSpecialFolder        TargetSource = "SpecialFolderDataBlock"
+	SourceIDList               TargetSource = "LinkTargetIDList"
+	SourceRelativePath         TargetSource = "StringData.RelativePath"
+)
+
+// TargetInfo is the target of the lnk file and where it was found.
+type TargetInfo struct {
+	// Path may contain environment variables (e.g. %windir%) if it's from
+	// the EnvironmentVariableDataBlock, virtual folders (e.g. My Computer) if
+	// it's from the IDList or be relative to the lnk file if it's from
+	// RelativePath.
+	Path   string
+	Source TargetSource
+}
+
+// Signatures of the blocks used to find the target.
+const (
+	environmentSignature   = 0xA0000001
+	specialFolderSignature = 0xA0000005
+	knownFolderSignature   = 0xA000000B
+)
+
+// Target returns the best target path of the lnk file. Sources are checked in
+// this order and the first non-empty path is returned:
+//
+//  1. EnvironmentVariableDataBlock if PreferEnvironmentPath is set.
+//  2. LinkInfo (LocalBasePath and CommonPathSuffix or NetName and
+//     CommonPathSuffix) unless ForceNoLinkInfo is set. Unicode fields are
+//     preferred.
+//  3. EnvironmentVariableDataBlock if HasExpString is set.
+//  4. KnownFolderDataBlock and SpecialFolderDataBlock unless
+//     DisableKnownFolderTracking is set.
+//  5. LinkTargetIDList.
+//  6. RelativePath.
+//
+// The EnvironmentVariableDataBlock is ignored if DisableLinkPathTracking is
+// set. Source is SourceNone if the target was not found.
+func (f LnkFile) Target() TargetInfo {
+	flags := f.Header.LinkFlags
+	useEnv := flags["HasExpString"] && !flags["DisableLinkPathTracking"]
+
+	if useEnv && flags["PreferEnvironmentPath"] {
+		if p := f.environmentPath(); p != "" {
+			return TargetInfo{Path: p, Source: SourceEnvironment}
+		}
+	}
+
+	if flags["HasLinkInfo"] && !flags["ForceNoLinkInfo"] {
+		if t := f.LinkInfo.target(); t.Path != "" {
+			return t
+		}
+	}
+
+	if useEnv {
+		if p := f.environmentPath(); p != "" {
+			return TargetInfo{Path: p, Source: SourceEnvironment}
+		}
+	}
+
+	if !flags["DisableKnownFolderTracking"] {
+		if t := f.folderTarget(); t.Path != "" {
+			return t
+		}
+	}
+
+	if p := f.IDList.Path(); p != "" {
+		return TargetInfo{Path: p, Source: SourceIDList}
+	}
+
+	if f.StringData.RelativePath != "" {
+		return TargetInfo{Path: f.StringData.RelativePath, Source: SourceRelativePath}
+	}
+	return TargetInfo{}
+}
+
+// target returns the local or network path in LinkInfo.
+func (li LinkInfoSection) target() TargetInfo {
+	suffix := li.CommonPathSuffix
+	if li.CommonPathSuffixUnicode != "" {
+		suffix = li.CommonPathSuffixUnicode
+	}
+
+	// VolumeIDAndLocalBasePath.
+	if bitMaskuint32(li.LinkInfoFlags, 0) {
+		if li.LocalBasePathUnicode != "" {
+			return TargetInfo{Path: joinPath(li.LocalBasePathUnicode, suffix), Source: SourceLocalBasePathUnicode}
+		}
+		if li.LocalBasePath != "" {
+			return TargetInfo{Path: joinPath(li.LocalBasePath, suffix), Source: SourceLocalBasePath}
+		}
+	}
+	// CommonNetworkRelativeLinkAndPathSuffix.
+	if bitMaskuint32(li.LinkInfoFlags, 1) {
+		nl := li.NetworkRelativeLink
+		if nl.NetNameUnicode != "" {
+			return TargetInfo{Path: joinPath(nl.NetNameUnicode, suffix), Source: SourceNetNameUnicode}
+		}
+		if nl.NetName != "" {
+			return TargetInfo{Path: joinPath(nl.NetName, suffix), Source: SourceNetName}
+		}
+	}
+	return TargetInfo{}
+}
+
+// joinPath adds suffix to base with a backslash between them.
+func joinPath(base, suffix string) string {
+	if suffix == "" {
+		return base
+	}
+	return strings.TrimRight(base, `\`) + `\` + strings.TrimLeft(suffix, `\`)
+}
+
+// environment returns the parsed EnvironmentVariableDataBlock.
+func (f LnkFile) environment() (EnvironmentVariableDataBlock, bool) {
+	b, _ := f.DataBlocks.Block(environmentSignature)
+	env, ok := b.Parsed.(EnvironmentVariableDataBlock)
+	return env, ok
+}
+
+// environmentPath returns the target in the EnvironmentVariableDataBlock.
+// The Unicode path is preferred.
+func (f LnkFile) environmentPath() string {
+	env, _ := f.environment()
+	if env.TargetUnicode != "" {
+		return env.TargetUnicode
+	}
+	return env.TargetAnsi
+}
+
+// folderTarget returns the path from the KnownFolderDataBlock or the
+// SpecialFolderDataBlock. Both blocks have the folder and an offset into the
+// IDList where the items inside the folder start.
+func (f LnkFile) folderTarget() TargetInfo {
+	b, _ := f.DataBlocks.Block(knownFolderSignature)
+	if k, ok := b.Parsed.(KnownFolderDataBlock); ok {
+		if rest, ok := f.IDList.PathFrom(k.Offset); ok {
+			return TargetInfo{Path: joinPath(k.Name, rest), Source: SourceKnownFolder}
+		}
+	}
+	b, _ = f.DataBlocks.Block(specialFolderSignature)
+	if s, ok := b.Parsed.(SpecialFolderDataBlock); ok {
+		if rest, ok := f.IDList.PathFrom(s.Offset); ok {
+			return TargetInfo{Path: joinPath(s.Name, rest), Source: SourceSpecialFolder}
+		}
+	}
+	return TargetInfo{}
+}
@@ -0,0 +1,42 @@
+package lnk
+
+import "testing"
+
+func TestTarget(t *testing.T) {
+	tests := []struct {
+		name     string
+		filename string
+		modify   func(f *LnkFile) // Changes the file before calling Target.
+		want     TargetInfo
+	}{
+		{"localbasepath", "test/nem.test", nil,
+			TargetInfo{`C:\Users\e\Desktop\2016-01-28-083758-HasherResults.xlsx`, SourceLocalBasePath}},
+		{"environment", "test/Windows Store.lnk", nil,
+			TargetInfo{`%windir%\WinStore\WinStore.htm`, SourceEnvironment}},
+		{"environment-disabled", "test/Windows Store.lnk",
+			func(f *LnkFile) { f.Header.LinkFlags["DisableLinkPathTracking"] = true },
+			TargetInfo{}},
+		{"forcenolinkinfo", "test/test.lnk.bak", nil,
+			TargetInfo{`Users Files\AppData\Local\Programs\Microsoft VS Code\Code.exe`, SourceKnownFolder}},
+		{"knownfolder-disabled", "test/test.lnk.bak",
+			func(f *LnkFile) { f.Header.LinkFlags["DisableKnownFolderTracking"] = true },
+			TargetInfo{`Users Files\AppData\Local\Programs\Microsoft VS Code\Code.exe`, SourceIDList}},
+		{"relativepath", "test/test.lnk.bak",
+			func(f *LnkFile) { f.IDList = LinkTargetIDListSection{} },
+			TargetInfo{`..\..\..\..\..\..\Local\Programs\Microsoft VS Code\Code.exe`, SourceRelativePath}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := File(tt.filename)
+			if err != nil {
+				t.Fatalf("File() error = %v", err)
+			}
+			if tt.modify != nil {
+				tt.modify(&f)
+			}
+			if got := f.Target(); got != tt.want {
+				t.Errorf("Target() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
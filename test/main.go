@@ -1,3 +1,6 @@
+//go:build ignore
+// +build ignore
+
 package main
 
 import (
@@ -61,7 +64,12 @@ func main() {
 	// _ = edb
 	// // fmt.Println(lnk.StructToJSON(edb, true))
 
-	ln, err := lnk.Read(fi)
+	info, err := fi.Stat()
+	if err != nil {
+		panic(err)
+	}
+
+	ln, err := lnk.Read(fi, uint64(info.Size()))
 	if err != nil {
 		panic(err)
 	}
@@ -1,9 +1,11 @@
+//go:build ignore
+// +build ignore
+
 package main
 
 import (
+	"context"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"github.com/parsiya/golnk"
 )
@@ -16,31 +18,17 @@ func main() {
 
 	basePaths := []string{}
 
-	err := filepath.Walk(startMenu, func(path string, info os.FileInfo, walkErr error) error {
-		// Only look for lnk files.
-		if filepath.Ext(info.Name()) == ".lnk" {
-			f, lnkErr := lnk.File(path)
-			// Print errors and move on to the next file.
-			if lnkErr != nil {
-				fmt.Println(lnkErr)
-				return nil
-			}
-			var targetPath = ""
-			if f.LinkInfo.LocalBasePath != "" {
-				targetPath = f.LinkInfo.LocalBasePath
-			}
-			if f.LinkInfo.LocalBasePathUnicode != "" {
-				targetPath = f.LinkInfo.LocalBasePathUnicode
-			}
-			if targetPath != "" {
-				fmt.Println("Found", targetPath)
-				basePaths = append(basePaths, targetPath)
-			}
+	for res := range lnk.Scan(context.Background(), startMenu, lnk.ScanOptions{}) {
+		// Print errors and move on to the next file.
+		if res.Err != nil {
+			fmt.Println(res.Err)
+			continue
+		}
+		targetPath := res.File.Target().Path
+		if targetPath != "" {
+			fmt.Println("Found", targetPath)
+			basePaths = append(basePaths, targetPath)
 		}
-		return nil
-	})
-	if err != nil {
-		panic(err)
 	}
 
 	// Print everything.
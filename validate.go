@@ -0,0 +1,377 @@
+package lnk
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Severity of a Finding.
+type Severity int
+
+// Severities from the least to the most serious.
+const (
+	// SeverityInfo is allowed by the specification but unusual.
+	SeverityInfo Severity = iota
+	// SeverityWarning breaks a MUST or SHOULD that Windows ignores.
+	SeverityWarning
+	// SeverityError is a structure that Windows cannot load correctly.
+	SeverityError
+)
+
+// String returns info, warning or error.
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	}
+	return "error"
+}
+
+// MarshalText returns String so severities are readable in JSON.
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Finding is a deviation from [MS-SHLLINK] reported by Validate.
+type Finding struct {
+	// Rule is the stable rule ID, e.g. LNK101. See rules for the list.
+	Rule     string
+	Severity Severity
+	// Section is the structure name from the specification.
+	Section string
+	Message string
+}
+
+// String returns the finding in one line.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s %s: %s", f.Rule, f.Severity, f.Section, f.Message)
+}
+
+// rule is the severity and section of a rule ID.
+type rule struct {
+	severity Severity
+	section  string
+}
+
+// rules are the checks done by Validate. IDs must not change. The first digit
+// is the section: 0 parsing, 1 header, 2 LinkTargetIDList, 3 LinkInfo,
+// 4 StringData and 5 ExtraData. The section of the parsing rules is the
+// section of the warning.
+var rules = map[string]rule{
+	"LNK001": {SeverityError, ""},   // A section could not be parsed, see LnkFile.Warnings.
+	"LNK002": {SeverityWarning, ""}, // A string has invalid UTF-16.
+
+	"LNK101": {SeverityWarning, "ShellLinkHeader"}, // Reserved1 is not zero.
+	"LNK102": {SeverityWarning, "ShellLinkHeader"}, // Reserved2 is not zero.
+	"LNK103": {SeverityWarning, "ShellLinkHeader"}, // Reserved3 is not zero.
+	"LNK104": {SeverityInfo, "ShellLinkHeader"},    // Undefined LinkFlags bits are set.
+	"LNK105": {SeverityWarning, "ShellLinkHeader"}, // Reserved FileAttributes bits are set.
+	"LNK106": {SeverityWarning, "ShellLinkHeader"}, // ShowCommand is not 1, 3 or 7.
+
+	"LNK201": {SeverityError, "LinkTargetIDList"},   // IDListSize does not match the ItemIDs.
+	"LNK202": {SeverityWarning, "LinkTargetIDList"}, // HasLinkTargetIDList is set but the list is empty or missing.
+
+	"LNK301": {SeverityError, "LinkInfo"},                    // LinkInfoHeaderSize is not 0x1C or at least 0x24.
+	"LNK302": {SeverityError, "LinkInfo"},                    // An offset is outside LinkInfoSize.
+	"LNK303": {SeverityWarning, "LinkInfo"},                  // VolumeIDAndLocalBasePath does not match the offsets.
+	"LNK304": {SeverityWarning, "LinkInfo"},                  // CommonNetworkRelativeLinkAndPathSuffix does not match the offset.
+	"LNK305": {SeverityWarning, "LinkInfo"},                  // Undefined LinkInfoFlags bits are set.
+	"LNK306": {SeverityWarning, "LinkInfo"},                  // HasLinkInfo is set but the LinkInfo is empty or missing.
+	"LNK311": {SeverityError, "VolumeID"},                    // VolumeLabelOffsetUnicode is missing or outside VolumeIDSize.
+	"LNK312": {SeverityError, "VolumeID"},                    // VolumeLabelOffset is outside VolumeIDSize.
+	"LNK313": {SeverityWarning, "VolumeID"},                  // DriveType is not defined.
+	"LNK321": {SeverityWarning, "CommonNetworkRelativeLink"}, // ValidDevice does not match DeviceNameOffset.
+	"LNK322": {SeverityWarning, "CommonNetworkRelativeLink"}, // NetworkProviderType is set without ValidNetType.
+	"LNK323": {SeverityError, "CommonNetworkRelativeLink"},   // Offsets are missing or outside the size.
+	"LNK324": {SeverityWarning, "CommonNetworkRelativeLink"}, // Undefined flag bits are set.
+
+	"LNK401": {SeverityInfo, "StringData"},    // A string flag is set but the string is empty.
+	"LNK402": {SeverityWarning, "StringData"}, // A string flag is set but the StringData is missing.
+
+	"LNK501": {SeverityWarning, "ExtraData"}, // Unknown block signature.
+	"LNK502": {SeverityError, "ExtraData"},   // TerminalBlock is not less than 4.
+	"LNK503": {SeverityError, "ExtraData"},   // Block size does not match the block type.
+	"LNK504": {SeverityWarning, "ExtraData"}, // The same block type appears more than once.
+	"LNK505": {SeverityWarning, "ExtraData"}, // A LinkFlags bit is set without its block.
+	"LNK506": {SeverityWarning, "ExtraData"}, // A block exists without its LinkFlags bit.
+	"LNK507": {SeverityWarning, "ExtraData"}, // Data after the TerminalBlock.
+	"LNK508": {SeverityWarning, "ExtraData"}, // TargetAnsi and TargetUnicode of an environment block differ.
+}
+
+// blockFlags are the LinkFlags that say a block exists.
+var blockFlags = []struct {
+	flag string
+	sig  uint32
+}{
+	{"HasExpString", environmentSignature},
+	{"HasDarwinID", 0xA0000006},
+	{"HasExpIcon", 0xA0000007},
+	{"RunWithShimLayer", 0xA0000008},
+}
+
+// validator collects findings.
+type validator struct {
+	findings []Finding
+}
+
+// add adds a finding for the rule.
+func (v *validator) add(id, format string, args ...interface{}) {
+	r := rules[id]
+	v.findings = append(v.findings, Finding{
+		Rule:     id,
+		Severity: r.severity,
+		Section:  r.section,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// parseWarning adds a finding for a warning of the parser.
+func (v *validator) parseWarning(w ParseWarning) {
+	id := "LNK001"
+	if errors.Is(w.Err, ErrBadString) {
+		id = "LNK002"
+	}
+	v.add(id, "%s at offset 0x%X - %s - %s", w.Field, w.Offset, w.Err.Error(), w.Recovery)
+	v.findings[len(v.findings)-1].Section = w.Section
+}
+
+// Validate checks the file against [MS-SHLLINK] and returns every deviation.
+// It does not change the file. Sections are only checked if their LinkFlags
+// bit is set. The Warnings of a lenient parse are reported as LNK001 and
+// LNK002 and the rules of the sections that were not parsed are skipped.
+func (f LnkFile) Validate() []Finding {
+	var v validator
+	for _, w := range f.Warnings {
+		v.parseWarning(w)
+	}
+	v.header(f.Header)
+	if f.Header.LinkFlags["HasLinkTargetIDList"] {
+		v.idList(f.IDList)
+	}
+	if f.Header.LinkFlags["HasLinkInfo"] {
+		v.linkInfo(f.LinkInfo)
+	}
+	v.stringData(f.StringData, f.Header.LinkFlags)
+	v.extraData(f.DataBlocks, f.Header.LinkFlags)
+	if o := f.Overlay; o.Size > 0 {
+		v.add("LNK507", "%d bytes of %s after the TerminalBlock at offset 0x%X", o.Size, o.Type, o.Offset)
+	}
+	return v.findings
+}
+
+// header checks the ShellLinkHeader.
+func (v *validator) header(h ShellLinkHeaderSection) {
+	if h.Reserved1 != 0 {
+		v.add("LNK101", "Reserved1 is 0x%X, must be zero", h.Reserved1)
+	}
+	if h.Reserved2 != 0 {
+		v.add("LNK102", "Reserved2 is 0x%X, must be zero", h.Reserved2)
+	}
+	if h.Reserved3 != 0 {
+		v.add("LNK103", "Reserved3 is 0x%X, must be zero", h.Reserved3)
+	}
+	if h.FileAttributes["Reserved1"] || h.FileAttributes["Reserved2"] {
+		v.add("LNK105", "FileAttributes Reserved1 or Reserved2 is set, must be zero")
+	}
+
+	// Raw values that are not kept in the fields.
+	if len(h.Raw) < headerSize {
+		return
+	}
+	if lf := binary.LittleEndian.Uint32(h.Raw[0x14:]); lf>>uint(len(linkFlags)) != 0 {
+		v.add("LNK104", "LinkFlags 0x%08X has undefined bits set", lf)
+	}
+	switch sw := binary.LittleEndian.Uint32(h.Raw[0x3C:]); sw {
+	case 0x01, 0x03, 0x07:
+	default:
+		v.add("LNK106", "ShowCommand is 0x%X, must be 0x1, 0x3 or 0x7", sw)
+	}
+}
+
+// idList checks the LinkTargetIDList.
+func (v *validator) idList(li LinkTargetIDListSection) {
+	if li.Span.Size == 0 {
+		v.add("LNK202", "HasLinkTargetIDList is set but there is no LinkTargetIDList")
+		return
+	}
+	// The size of the ItemIDs and the TerminalID.
+	size := 2
+	for _, it := range li.List.ItemIDList {
+		size += int(it.Size)
+	}
+	if size != int(li.IDListSize) {
+		v.add("LNK201", "IDListSize is %d, the ItemIDs use %d bytes", li.IDListSize, size)
+	}
+	if len(li.List.ItemIDList) == 0 {
+		v.add("LNK202", "HasLinkTargetIDList is set but the IDList has no ItemIDs")
+	}
+}
+
+// linkInfo checks LinkInfo, VolumeID and CommonNetworkRelativeLink.
+func (v *validator) linkInfo(li LinkInfoSection) {
+	if li.Span.Size == 0 {
+		v.add("LNK306", "HasLinkInfo is set but there is no LinkInfo")
+		return
+	}
+	if li.LinkInfoFlags&0x03 == 0 {
+		v.add("LNK306", "HasLinkInfo is set but the LinkInfo has no VolumeID or CommonNetworkRelativeLink")
+	}
+	if li.LinkInfoHeaderSize != 0x1C && li.LinkInfoHeaderSize < 0x24 {
+		v.add("LNK301", "LinkInfoHeaderSize is 0x%X, must be 0x1C or at least 0x24", li.LinkInfoHeaderSize)
+	}
+	if li.LinkInfoFlags>>2 != 0 {
+		v.add("LNK305", "LinkInfoFlags 0x%X has undefined bits set", li.LinkInfoFlags)
+	}
+
+	offsets := []struct {
+		name   string
+		offset uint32
+	}{
+		{"VolumeIDOffset", li.VolumeIDOffset},
+		{"LocalBasePathOffset", li.LocalBasePathOffset},
+		{"CommonNetworkRelativeLinkOffset", li.CommonNetworkRelativeLinkOffset},
+		{"CommonPathSuffixOffset", li.CommonPathSuffixOffset},
+		{"LocalBasePathOffsetUnicode", li.LocalBasePathOffsetUnicode},
+		{"CommonPathSuffixOffsetUnicode", li.CommonPathSuffixOffsetUnicode},
+	}
+	for _, o := range offsets {
+		if o.offset >= li.Size && o.offset != 0 {
+			v.add("LNK302", "%s 0x%X is outside LinkInfoSize 0x%X", o.name, o.offset, li.Size)
+		}
+	}
+
+	hasVolume := bitMaskuint32(li.LinkInfoFlags, 0)
+	switch {
+	case hasVolume && (li.VolumeIDOffset == 0 || li.LocalBasePathOffset == 0):
+		v.add("LNK303", "VolumeIDAndLocalBasePath is set but VolumeIDOffset or LocalBasePathOffset is zero")
+	case !hasVolume && (li.VolumeIDOffset != 0 || li.LocalBasePathOffset != 0 || li.LocalBasePathOffsetUnicode != 0):
+		v.add("LNK303", "VolumeIDAndLocalBasePath is not set but the VolumeID or LocalBasePath offsets are not zero")
+	}
+
+	hasNetwork := bitMaskuint32(li.LinkInfoFlags, 1)
+	switch {
+	case hasNetwork && li.CommonNetworkRelativeLinkOffset == 0:
+		v.add("LNK304", "CommonNetworkRelativeLinkAndPathSuffix is set but CommonNetworkRelativeLinkOffset is zero")
+	case !hasNetwork && li.CommonNetworkRelativeLinkOffset != 0:
+		v.add("LNK304", "CommonNetworkRelativeLinkAndPathSuffix is not set but CommonNetworkRelativeLinkOffset is 0x%X",
+			li.CommonNetworkRelativeLinkOffset)
+	}
+
+	// The structures are missing if they could not be parsed.
+	if hasVolume && li.VolumeIDOffset != 0 && li.VolID.Span.Size > 0 {
+		v.volumeID(li.VolID)
+	}
+	if hasNetwork && li.CommonNetworkRelativeLinkOffset != 0 && li.NetworkRelativeLink.Span.Size > 0 {
+		v.network(li.NetworkRelativeLink)
+	}
+}
+
+// volumeID checks the VolumeID.
+func (v *validator) volumeID(vol VolID) {
+	if vol.VolumeLabelOffset == 0x14 {
+		if vol.VolumeLabelOffsetUnicode == 0 || vol.VolumeLabelOffsetUnicode >= vol.Size {
+			v.add("LNK311", "VolumeLabelOffset is 0x14 but VolumeLabelOffsetUnicode 0x%X is missing or outside VolumeIDSize 0x%X",
+				vol.VolumeLabelOffsetUnicode, vol.Size)
+		}
+	} else if vol.VolumeLabelOffset >= vol.Size {
+		v.add("LNK312", "VolumeLabelOffset 0x%X is outside VolumeIDSize 0x%X", vol.VolumeLabelOffset, vol.Size)
+	}
+	if vol.DriveType == "DRIVE_INVALID" {
+		v.add("LNK313", "DriveType is not defined")
+	}
+}
+
+// network checks the CommonNetworkRelativeLink.
+func (v *validator) network(c CommonNetworkRelativeLink) {
+	flags := c.CommonNetworkRelativeLinkFlags
+	if flags>>2 != 0 {
+		v.add("LNK324", "CommonNetworkRelativeLinkFlags 0x%X has undefined bits set", flags)
+	}
+
+	validDevice := bitMaskuint32(flags, 0)
+	if validDevice != (c.DeviceNameOffset != 0) {
+		v.add("LNK321", "ValidDevice is %v but DeviceNameOffset is 0x%X", validDevice, c.DeviceNameOffset)
+	}
+	if !bitMaskuint32(flags, 1) && networkProviderValue(c.NetworkProviderType) != 0 {
+		v.add("LNK322", "ValidNetType is not set but NetworkProviderType is %s", c.NetworkProviderType)
+	}
+
+	if c.NetNameOffset == 0 || c.NetNameOffset >= c.Size {
+		v.add("LNK323", "NetNameOffset 0x%X is missing or outside the size 0x%X", c.NetNameOffset, c.Size)
+	}
+	if c.DeviceNameOffset >= c.Size && c.DeviceNameOffset != 0 {
+		v.add("LNK323", "DeviceNameOffset 0x%X is outside the size 0x%X", c.DeviceNameOffset, c.Size)
+	}
+	if c.NetNameOffset > 0x14 {
+		if c.NetNameOffsetUnicode == 0 || c.NetNameOffsetUnicode >= c.Size {
+			v.add("LNK323", "NetNameOffset is larger than 0x14 but NetNameOffsetUnicode 0x%X is missing or outside the size 0x%X",
+				c.NetNameOffsetUnicode, c.Size)
+		}
+		if c.DeviceNameOffsetUnicode >= c.Size {
+			v.add("LNK323", "DeviceNameOffsetUnicode 0x%X is outside the size 0x%X", c.DeviceNameOffsetUnicode, c.Size)
+		}
+	}
+}
+
+// stringData checks StringData.
+func (v *validator) stringData(st StringDataSection, flags FlagMap) {
+	strs := []struct {
+		flag string
+		str  string
+	}{
+		{"HasName", st.NameString},
+		{"HasRelativePath", st.RelativePath},
+		{"HasWorkingDir", st.WorkingDir},
+		{"HasArguments", st.CommandLineArguments},
+		{"HasIconLocation", st.IconLocation},
+	}
+	for _, s := range strs {
+		switch {
+		case !flags[s.flag]:
+		case st.Span.Size == 0:
+			v.add("LNK402", "%s is set but there is no StringData", s.flag)
+		case s.str == "":
+			v.add("LNK401", "%s is set but the string is empty", s.flag)
+		}
+	}
+}
+
+// extraData checks the ExtraData blocks.
+func (v *validator) extraData(e ExtraDataSection, flags FlagMap) {
+	if e.TerminalBlock >= 0x04 {
+		v.add("LNK502", "TerminalBlock is 0x%X, must be less than 0x4", e.TerminalBlock)
+	}
+
+	seen := map[uint32]bool{}
+	for _, b := range e.Blocks {
+		size, fixed := blockSizes[b.Signature]
+		min, variable := blockMinSizes[b.Signature]
+		switch {
+		case !fixed && !variable:
+			v.add("LNK501", "unknown block signature 0x%08X", b.Signature)
+		case fixed && b.Size != size:
+			v.add("LNK503", "%s size is 0x%X, must be 0x%X", b.Type, b.Size, size)
+		case variable && b.Size < min:
+			v.add("LNK503", "%s size is 0x%X, must be at least 0x%X", b.Type, b.Size, min)
+		}
+		if m, ok := b.Parsed.(interface{ Mismatch() bool }); ok && m.Mismatch() {
+			v.add("LNK508", "%s TargetAnsi and TargetUnicode are different", b.Type)
+		}
+		if seen[b.Signature] {
+			v.add("LNK504", "%s appears more than once", b.Type)
+		}
+		seen[b.Signature] = true
+	}
+
+	for _, bf := range blockFlags {
+		switch {
+		case flags[bf.flag] && !seen[bf.sig]:
+			v.add("LNK505", "%s is set but there is no %s", bf.flag, blockSignature(bf.sig))
+		case !flags[bf.flag] && seen[bf.sig]:
+			v.add("LNK506", "%s exists but %s is not set", blockSignature(bf.sig), bf.flag)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package lnk
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	// Files saved by Windows have no findings.
+	for _, name := range []string{"test/test.lnk", "test/remote.file.xp.test", "test/Windows Store.lnk"} {
+		f, err := File(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := f.Validate(); len(got) != 0 {
+			t.Errorf("%s: Validate() = %v, want none", name, got)
+		}
+	}
+
+	// The modify functions change the bytes of the file. at returns the
+	// offset of a field in the original file.
+	tests := []struct {
+		name   string
+		file   string
+		modify func(b []byte, at func(field string) int64) []byte
+		want   []string
+	}{
+		{"reserved1", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ShellLinkHeader.Reserved1")] = 1
+			return b
+		}, []string{"LNK101"}},
+		{"reserved3", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ShellLinkHeader.Reserved3")] = 1
+			return b
+		}, []string{"LNK103"}},
+		{"showcommand", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ShellLinkHeader.ShowCommand")] = 0x05
+			return b
+		}, []string{"LNK106"}},
+		{"idlist-missing", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("LinkTargetIDList.IDList.ItemID[0]")+1] = 0xFF
+			return b
+		}, []string{"LNK001", "LNK202"}},
+		{"headersize", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("LinkInfo.LinkInfoHeaderSize")] = 0x20
+			return b
+		}, []string{"LNK301"}},
+		{"offset", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			copy(b[at("LinkInfo.CommonPathSuffixOffset"):], b[at("LinkInfo.LinkInfoSize"):][:4])
+			return b
+		}, []string{"LNK302"}},
+		{"volume-flag", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("LinkInfo.LinkInfoFlags")] = 0
+			return b
+		}, []string{"LNK306", "LNK303"}},
+		// The VolumeID rules are skipped, the VolumeID was not parsed.
+		{"volume-missing", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("LinkInfo.VolumeIDOffset")] = 0xFF
+			return b
+		}, []string{"LNK001", "LNK302"}},
+		{"volume-label", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("LinkInfo.VolumeID.VolumeLabelOffset")] = 0x13
+			return b
+		}, []string{"LNK312"}},
+		{"linkinfo-missing", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("LinkInfo.LinkInfoSize")+2] = 0xFF
+			return b
+		}, []string{"LNK001", "LNK306", "LNK402", "LNK402"}},
+		{"empty-string", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("StringData.WorkingDir.CountCharacters")] = 0
+			return b
+		}, []string{"LNK001", "LNK401"}},
+		{"signature", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ExtraData.KnownFolderDataBlock.BlockSignature")] = 0xFF
+			return b
+		}, []string{"LNK501"}},
+		// A TerminalBlock of 4 or more is read as a block.
+		{"terminal", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ExtraData.TerminalBlock")] = 0x04
+			return b
+		}, []string{"LNK001"}},
+		{"block-size", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ExtraData.KnownFolderDataBlock.BlockSize")]++
+			return b
+		}, []string{"LNK001", "LNK503"}},
+		{"duplicate", "test/test.lnk", func(b []byte, at func(string) int64) []byte {
+			block, next := at("ExtraData.KnownFolderDataBlock"), at("ExtraData.TrackerDataBlock")
+			return append(b[:next:next], b[block:]...)
+		}, []string{"LNK504"}},
+		{"flag-without-block", "test/Windows Store.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ShellLinkHeader.LinkFlags")+1] |= 0x10 // HasDarwinID
+			return b
+		}, []string{"LNK505"}},
+		{"block-without-flag", "test/Windows Store.lnk", func(b []byte, at func(string) int64) []byte {
+			b[at("ShellLinkHeader.LinkFlags")+1] &^= 0x02 // HasExpString
+			return b
+		}, []string{"LNK506"}},
+		{"block-bad-string", "test/Windows Store.lnk", func(b []byte, at func(string) int64) []byte {
+			copy(b[at("ExtraData.EnvironmentVariableDataBlock.TargetUnicode"):], []byte{0x00, 0xDC})
+			return b
+		}, []string{"LNK002", "LNK508"}},
+		{"environment-mismatch", "test/Windows Store.lnk", func(b []byte, at func(string) int64) []byte {
+			copy(b[at("ExtraData.EnvironmentVariableDataBlock.TargetAnsi"):], "C:\\Windows\\System32\\cmd.exe\x00")
+			return b
+		}, []string{"LNK508"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := Read(bytes.NewReader(data), uint64(len(data)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			spans := make(map[string]Span)
+			for _, fi := range f.Offsets() {
+				spans[fi.Name] = fi.Span
+			}
+			at := func(field string) int64 {
+				s, ok := spans[field]
+				if !ok {
+					t.Fatalf("%s has no field %s", tt.file, field)
+				}
+				return s.Offset
+			}
+
+			b := tt.modify(data, at)
+			f, err = Read(bytes.NewReader(b), uint64(len(b)), Lenient())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, fi := range f.Validate() {
+				if _, ok := rules[fi.Rule]; !ok {
+					t.Errorf("finding %s has no rule", fi)
+				}
+				got = append(got, fi.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Validate() = %v, want %v", f.Validate(), tt.want)
+			}
+		})
+	}
+}